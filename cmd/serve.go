@@ -52,6 +52,7 @@ var serveCmd = &cobra.Command{
 			AppID:          viper.GetInt64("github.appid"),
 			PrivateKeyFile: viper.GetString("github.keyfile"),
 			GithubSecret:   []byte(viper.GetString("github.secret")),
+			APIToken:       viper.GetString("server.apitoken"),
 			Port:           viper.GetInt32("server.port"),
 			Logger:         logrus.NewEntry(logger),
 		}
@@ -69,6 +70,7 @@ func init() {
 
 	serveCmd.PersistentFlags().Int32("port", 80, "Port for the server to listen on")
 	serveCmd.PersistentFlags().String("log-level", "", "The log level of the server")
+	serveCmd.PersistentFlags().String("api-token", "", "Bearer token for the REST API. The API is disabled without a token")
 
 	viper.BindPFlag("github.appid", serveCmd.PersistentFlags().Lookup("app-id"))
 	viper.BindPFlag("github.keyfile", serveCmd.PersistentFlags().Lookup("key-file"))
@@ -76,6 +78,7 @@ func init() {
 
 	viper.BindPFlag("server.port", serveCmd.PersistentFlags().Lookup("port"))
 	viper.BindPFlag("server.loglevel", serveCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("server.apitoken", serveCmd.PersistentFlags().Lookup("api-token"))
 
 	rootCmd.AddCommand(serveCmd)
 }
@@ -21,6 +21,7 @@ type ServerImpl struct {
 	AppID          int64
 	PrivateKeyFile string
 	GithubSecret   []byte
+	APIToken       string
 	Port           int32
 	Logger         *logrus.Entry
 }
@@ -41,7 +42,7 @@ func (s *ServerImpl) Serve() error {
 	}))
 
 	g := e.Group("/v1")
-	v1.Register(g, atr, s.GithubSecret, s.Logger.WithField("subsystem", "handler"))
+	v1.Register(g, atr, s.GithubSecret, s.APIToken, s.Logger.WithField("subsystem", "handler"))
 	e.GET("/", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Ready to receive")
 	})
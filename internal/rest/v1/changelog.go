@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v43/github"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/uniwise/go-ship-it/internal/scm"
+)
+
+const mimeMarkdown = "text/markdown"
+
+// GetChangelog summarises the changes of a repository between two refs, as JSON
+// or as Markdown rendered with the changelog template of the repository
+func (h *Handler) GetChangelog(c echo.Context, entry *logrus.Entry) error {
+	ctx := c.Request().Context()
+	owner, name := c.Param("owner"), c.Param("repo")
+	l := entry.WithField("repo", fmt.Sprintf("%s/%s", owner, name))
+
+	apps := github.NewClient(&http.Client{Transport: h.AppsTransport, Timeout: time.Minute})
+	installation, _, err := apps.Apps.FindRepositoryInstallation(ctx, owner, name)
+	if err != nil {
+		l.WithError(err).Debug("Could not find installation for repository")
+
+		return c.String(http.StatusNotFound, "App is not installed on repository")
+	}
+	k := ghinstallation.NewFromAppsTransport(h.AppsTransport, installation.GetID())
+	hc := &http.Client{Transport: k, Timeout: time.Minute}
+	repo, _, err := github.NewClient(hc).Repositories.Get(ctx, owner, name)
+	if err != nil {
+		l.WithError(err).Error("Could not get repository")
+
+		return err
+	}
+
+	ref := c.QueryParam("to")
+	if ref == "" {
+		ref = repo.GetDefaultBranch()
+	}
+	r, err := scm.NewReleaser(ctx, scm.NewGithubClient(hc, repo), ref, l)
+	if err != nil {
+		if errors.Is(err, scm.ErrConfMissing) {
+			return c.String(http.StatusNotFound, ".ship-it missing from repo")
+		}
+		l.WithError(err).Error("Could not initialize releaser")
+
+		return err
+	}
+
+	summary, err := r.Summarize(ctx, c.QueryParam("component"), c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		if errors.Is(err, scm.ErrComponentMissing) || errors.Is(err, scm.ErrNoRelease) {
+			return c.String(http.StatusNotFound, err.Error())
+		}
+		l.WithError(err).Error("Could not summarize changes")
+
+		return err
+	}
+
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), mimeMarkdown) {
+		body, err := summary.Markdown(ctx)
+		if err != nil {
+			l.WithError(err).Error("Could not render changelog")
+
+			return err
+		}
+
+		return c.Blob(http.StatusOK, fmt.Sprintf("%s; charset=UTF-8", mimeMarkdown), []byte(body))
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
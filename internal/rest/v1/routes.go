@@ -1,14 +1,21 @@
 package v1
 
 import (
+	"crypto/subtle"
+	"sync"
+
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
 	Secret        []byte
 	AppsTransport *ghinstallation.AppsTransport
+
+	slug   string
+	slugMu sync.Mutex
 }
 
 func NewHandler(atr *ghinstallation.AppsTransport, secret []byte) *Handler {
@@ -18,11 +25,19 @@ func NewHandler(atr *ghinstallation.AppsTransport, secret []byte) *Handler {
 	}
 }
 
-func Register(g *echo.Group, transport *ghinstallation.AppsTransport, secret []byte, l *logrus.Entry) {
+func Register(g *echo.Group, transport *ghinstallation.AppsTransport, secret []byte, apiToken string, l *logrus.Entry) {
 	h := NewHandler(transport, secret)
 
 	g.POST("/github", wrap(h.HandleGithub, l))
 	g.File("/schema", "assets/schema/v1.json")
+
+	// The API reads any repository the app is installed on, so it is only served
+	// when a token is configured
+	if apiToken != "" {
+		g.GET("/repos/:owner/:repo/changelog", wrap(h.GetChangelog, l), middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(apiToken)) == 1, nil
+		}))
+	}
 }
 
 type handlerFunc func(echo.Context, *logrus.Entry) error
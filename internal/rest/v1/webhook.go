@@ -13,6 +13,16 @@ import (
 	"github.com/uniwise/go-ship-it/internal/scm"
 )
 
+// pullActions are the pull request actions that may change the outcome of a check
+var pullActions = map[string]bool{
+	"opened":      true,
+	"reopened":    true,
+	"edited":      true,
+	"labeled":     true,
+	"unlabeled":   true,
+	"synchronize": true,
+}
+
 type HandledGithubEvent interface {
 	GetInstallation() *github.Installation
 }
@@ -24,6 +34,23 @@ func (h *Handler) initReleaser(c echo.Context, ev HandledGithubEvent, repo scm.R
 	return scm.NewReleaser(c.Request().Context(), client, ref, entry)
 }
 
+// appSlug looks up the slug of the app, which names its bot user. The slug is
+// cached once found
+func (h *Handler) appSlug(ctx context.Context) (string, error) {
+	h.slugMu.Lock()
+	defer h.slugMu.Unlock()
+	if h.slug != "" {
+		return h.slug, nil
+	}
+	app, _, err := github.NewClient(&http.Client{Transport: h.AppsTransport, Timeout: time.Minute}).Apps.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	h.slug = app.GetSlug()
+
+	return h.slug, nil
+}
+
 func (h *Handler) HandleGithub(c echo.Context, entry *logrus.Entry) error {
 	payload, err := github.ValidatePayload(c.Request(), h.Secret)
 	if err != nil {
@@ -38,7 +65,13 @@ func (h *Handler) HandleGithub(c echo.Context, entry *logrus.Entry) error {
 	switch event := event.(type) {
 	case *github.PushEvent:
 		l := entry.WithField("repo", event.GetRepo().GetFullName())
-		r, err := h.initReleaser(c, event, event.GetRepo(), event.GetHead(), l)
+		if event.GetDeleted() {
+			l.Debugf("Ignoring deletion of '%s'", event.GetRef())
+
+			return c.String(http.StatusOK, "Deletion ignored")
+		}
+		// The configuration and changelog template are read from the pushed commit
+		r, err := h.initReleaser(c, event, event.GetRepo(), event.GetAfter(), l)
 		if err != nil {
 			if errors.Is(err, scm.ErrConfMissing) {
 				l.WithError(err).Debug("Configuration missing from repository. Discarding event")
@@ -68,6 +101,33 @@ func (h *Handler) HandleGithub(c echo.Context, entry *logrus.Entry) error {
 		go r.HandleRelease(context.Background(), event)
 
 		return c.String(http.StatusAccepted, "Handling release event")
+	case *github.PullRequestEvent:
+		l := entry.WithField("repo", event.GetRepo().GetFullName())
+		if !pullActions[event.GetAction()] {
+			l.Debugf("Ignoring pull request action '%s'", event.GetAction())
+
+			return c.String(http.StatusOK, "Pull request action ignored")
+		}
+		r, err := h.initReleaser(c, event, event.GetRepo(), event.GetPullRequest().GetBase().GetRef(), l)
+		if err != nil {
+			if errors.Is(err, scm.ErrConfMissing) {
+				l.WithError(err).Debug("Configuration missing from repository. Discarding event")
+
+				return c.String(http.StatusNotFound, ".ship-it missing from repo. Event discarded")
+			}
+			l.WithError(err).Error("Could not initialize releaser")
+
+			return err
+		}
+		app, err := h.appSlug(c.Request().Context())
+		if err != nil {
+			l.WithError(err).Error("Could not get app")
+
+			return err
+		}
+		go r.HandlePull(context.Background(), event, app)
+
+		return c.String(http.StatusAccepted, "Handling pull request event")
 	case *github.PingEvent:
 		return c.String(http.StatusOK, "pong")
 	default:
@@ -0,0 +1,120 @@
+package scm
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+var lineRx = regexp.MustCompile(`v?(?P<major>[0-9]+)(\.(?P<minor>[0-9]+))?(\.x)?$`)
+
+// Target describes what a push to a branch releases
+type Target struct {
+	Branch  string
+	Channel string
+	// Line is only set for maintenance branches
+	Line *Line
+}
+
+// Line is the range of versions released from a maintenance branch, e.g. 1.4.x
+type Line struct {
+	Name       string
+	Constraint *semver.Constraints
+	// Max is the highest level a release in the line may bump
+	Max Level
+}
+
+// maintenanceLine reads the line of a maintenance branch from the end of its name,
+// e.g. release/1.4 releases 1.4.x and release/2 releases 2.x
+func maintenanceLine(branch string) (*Line, error) {
+	matches := lineRx.FindStringSubmatch(branch)
+	if matches == nil {
+		return nil, errors.Errorf("Could not read a version line from branch '%s'", branch)
+	}
+	major, minor := matches[lineRx.SubexpIndex("major")], matches[lineRx.SubexpIndex("minor")]
+	name, max := fmt.Sprintf("%s.x", major), LevelMinor
+	if minor != "" {
+		name, max = fmt.Sprintf("%s.%s.x", major, minor), LevelPatch
+	}
+	constraint, err := semver.NewConstraint(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not create constraint for line '%s'", name)
+	}
+	return &Line{
+		Name:       name,
+		Constraint: constraint,
+		Max:        max,
+	}, nil
+}
+
+func (r *Releaser) branches() []BranchConf {
+	if len(r.config.Branches) == 0 {
+		return []BranchConf{{Name: r.config.TargetBranch}}
+	}
+	return r.config.Branches
+}
+
+func branchMatches(pattern, branch string) bool {
+	if pattern == branch {
+		return true
+	}
+	ok, _ := path.Match(pattern, branch)
+	return ok
+}
+
+func (r *Releaser) Match(ref string) bool {
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	for _, b := range r.branches() {
+		if branchMatches(b.Name, branch) {
+			return true
+		}
+	}
+	if r.config.Strategy.Type == "full-release" {
+		return false
+	}
+	for _, c := range r.config.Strategy.Channels {
+		if c.Branch != "" && branchMatches(c.Branch, branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// Channel picks the pre-release channel for pushes to a branch. Branches without
+// a channel of their own release to the first channel
+func (r *Releaser) Channel(ref string) ChannelConf {
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	for _, c := range r.config.Strategy.Channels {
+		if c.Branch != "" && branchMatches(c.Branch, branch) {
+			return c
+		}
+	}
+	return r.config.Strategy.Channels[0]
+}
+
+// Target resolves what a push to the ref releases
+func (r *Releaser) Target(ref string) (*Target, error) {
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	target := &Target{
+		Branch:  branch,
+		Channel: r.Channel(ref).Name,
+	}
+	for _, b := range r.branches() {
+		if !branchMatches(b.Name, branch) {
+			continue
+		}
+		if b.Maintenance {
+			line, err := maintenanceLine(branch)
+			if err != nil {
+				return nil, err
+			}
+			target.Line = line
+		}
+		break
+	}
+	return target, nil
+}
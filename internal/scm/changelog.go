@@ -0,0 +1,449 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v43/github"
+	"github.com/pkg/errors"
+)
+
+var changelogRx = regexp.MustCompile("```release-note(:(?P<kind>[A-Za-z]+))?(?P<note>[\\s\\S]*?)```")
+
+// Kinds of typed release-note blocks, e.g. ```release-note:breaking
+const (
+	NoteBreaking    = "breaking"
+	NoteSecurity    = "security"
+	NoteDeprecation = "deprecation"
+)
+
+// noteKinds are the typed kinds in the order of their changelog sections
+var noteKinds = []struct {
+	kind  string
+	title string
+	level Level
+}{
+	{NoteBreaking, "Breaking changes", LevelMajor},
+	{NoteSecurity, "Security", LevelPatch},
+	{NoteDeprecation, "Deprecations", LevelMinor},
+}
+
+// defaultChangelogTemplate renders the changelog of the legacy type, when no
+// changelog.template is configured
+const defaultChangelogTemplate = `Changes:
+{{range .Sections}}{{if .Noted}}{{if .Title}}
+### {{.Title}}
+{{end}}
+{{range .Noted}}- #{{.Number}} {{.Note}}
+{{end}}{{end}}{{end}}{{if .Dependencies}}
+### Dependency updates
+
+{{range .Dependencies}}- #{{.Pull}} {{.Change}}
+{{end}}{{end}}{{if .DirectCommits}}
+### Direct commits
+
+{{range .DirectCommits}}- {{.SHA}} {{.Subject}}
+{{end}}{{end}}{{if .Issues}}
+### Issues
+
+{{range .Issues}}- [{{.Key}}]({{.URL}}){{range $i, $p := .Pulls}}{{if $i}},{{end}} #{{$p}}{{end}}
+{{end}}{{end}}{{if .Contributors}}
+### Contributors
+
+{{range .Contributors}}- {{.}}
+{{end}}{{end}}{{if .NewContributors}}
+### New contributors
+
+{{range .NewContributors}}- {{.Mention}} made their first contribution in #{{.Pull}}
+{{end}}{{end}}`
+
+// otherSection holds the pull requests not matching any configured section
+const otherSection = "Other"
+
+var changelogFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// changelogData is available to changelog templates
+type changelogData struct {
+	Version     string
+	Tag         string
+	PreviousTag string
+	CompareURL  string
+	Pulls       []changelogPull
+	// Sections groups the pull requests by changelog.sections. Without sections,
+	// all pull requests are in a single untitled section
+	Sections []changelogSection
+	// Dependencies are the pull requests of dependency bots, which are left out of
+	// Pulls and Sections
+	Dependencies []changelogDependency
+	// DirectCommits are the commits pushed without a pull request
+	DirectCommits []changelogCommit
+	// Issues are the issues referenced by the pull requests, with changelog.issueLinks
+	Issues []changelogIssue
+	// Contributors and NewContributors are only set with changelog.contributors
+	Contributors    []string
+	NewContributors []newContributor
+}
+
+type changelogSection struct {
+	Title string
+	Pulls []changelogPull
+}
+
+type changelogPull struct {
+	Number int
+	Title  string
+	Author string
+	URL    string
+	Labels []string
+	// Note is the text of the release-note blocks of the pull request. Within a
+	// section it only holds the notes belonging to that section
+	Note  string
+	Notes []releaseNote
+}
+
+type changelogCommit struct {
+	SHA     string
+	Subject string
+	Author  string
+	URL     string
+}
+
+// releaseNote is a release-note block. Kind is empty for untyped blocks
+type releaseNote struct {
+	Kind string
+	Text string
+}
+
+func knownKind(kind string) bool {
+	for _, k := range noteKinds {
+		if k.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Level is the bump requested by the kind of the note
+func (n releaseNote) Level() Level {
+	for _, k := range noteKinds {
+		if k.kind == n.Kind {
+			return k.level
+		}
+	}
+	return LevelNone
+}
+
+// text joins the notes of a kind
+func (p changelogPull) text(kind string) string {
+	texts := []string{}
+	for _, n := range p.Notes {
+		if n.Kind == kind {
+			texts = append(texts, n.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Noted lists the pull requests of the section with a release note
+func (s changelogSection) Noted() []changelogPull {
+	noted := []changelogPull{}
+	for _, p := range s.Pulls {
+		if p.Note != "" {
+			noted = append(noted, p)
+		}
+	}
+	return noted
+}
+
+// loadChangelogTemplate parses the changelog template at the path in the
+// repository, or the default template when no path is given
+func loadChangelogTemplate(ctx context.Context, c GithubClient, ref, path string) (*template.Template, error) {
+	text := defaultChangelogTemplate
+	if path != "" {
+		reader, err := c.GetFile(ctx, ref, path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get changelog template '%s'", path)
+		}
+		defer reader.Close()
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read changelog template '%s'", path)
+		}
+		text = string(content)
+	}
+	tmpl, err := template.New("changelog").Option("missingkey=error").Funcs(changelogFuncs).Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse changelog template '%s'", path)
+	}
+	return tmpl, nil
+}
+
+// releaseNotes reads the release-note blocks of a pull request body. Blocks of
+// unknown kinds are read as untyped, and a NONE block opts out of every note
+func releaseNotes(body string) []releaseNote {
+	notes := []releaseNote{}
+	for _, matches := range changelogRx.FindAllStringSubmatch(body, -1) {
+		text := strings.TrimSpace(matches[changelogRx.SubexpIndex("note")])
+		if strings.EqualFold(text, "none") {
+			return []releaseNote{}
+		}
+		if text == "" {
+			continue
+		}
+		note := releaseNote{Kind: strings.ToLower(matches[changelogRx.SubexpIndex("kind")]), Text: text}
+		if !knownKind(note.Kind) {
+			note.Kind = ""
+		}
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+// compareURL links to the diff between two tags on GitHub
+func (r *Releaser) compareURL(previous, tag string) string {
+	if previous == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/compare/%s...%s", r.client.GetRepo().GetHTMLURL(), previous, tag)
+}
+
+// sections groups the notes of pull requests. Typed notes go to a section per
+// kind, or the configured section of the same title, while untyped notes go to the
+// first configured section matching any of the labels of their pull request, or
+// the Other section
+func (r *Releaser) sections(pulls []changelogPull) []changelogSection {
+	typed := []changelogSection{}
+	for _, k := range noteKinds {
+		section := changelogSection{Title: k.title, Pulls: []changelogPull{}}
+		for _, p := range pulls {
+			if text := p.text(k.kind); text != "" {
+				p.Note = text
+				section.Pulls = append(section.Pulls, p)
+			}
+		}
+		if len(section.Pulls) > 0 {
+			typed = append(typed, section)
+		}
+	}
+	untyped := []changelogPull{}
+	for _, p := range pulls {
+		p.Note = p.text("")
+		untyped = append(untyped, p)
+	}
+	pulls = untyped
+
+	if len(r.config.Changelog.Sections) == 0 {
+		title := ""
+		if len(typed) > 0 {
+			title = otherSection
+		}
+		return append(typed, changelogSection{Title: title, Pulls: pulls})
+	}
+	sections := []changelogSection{}
+	for _, s := range r.config.Changelog.Sections {
+		sections = append(sections, changelogSection{Title: s.Title, Pulls: []changelogPull{}})
+	}
+	other := changelogSection{Title: otherSection, Pulls: []changelogPull{}}
+	for _, p := range pulls {
+		matched := false
+		for i, s := range r.config.Changelog.Sections {
+			for _, l := range p.Labels {
+				if s.Labels.Has(l) {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				sections[i].Pulls = append(sections[i].Pulls, p)
+				break
+			}
+		}
+		if !matched {
+			other.Pulls = append(other.Pulls, p)
+		}
+	}
+	// Typed notes join a configured section of the same title, ahead of its notes
+	unmerged := []changelogSection{}
+	for _, t := range typed {
+		merged := false
+		for i := range sections {
+			if strings.EqualFold(sections[i].Title, t.Title) {
+				sections[i].Pulls = append(t.Pulls, sections[i].Pulls...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			unmerged = append(unmerged, t)
+		}
+	}
+	return append(unmerged, append(sections, other)...)
+}
+
+// DirectCommits lists the commits pushed without a pull request, leaving out those
+// opting out of releasing
+func (r *Releaser) DirectCommits(changes *Changes) []*github.RepositoryCommit {
+	commits := []*github.RepositoryCommit{}
+	for _, c := range changes.Commits {
+		if !changes.direct(c.GetSHA()) || r.commitSkipped(c, nil) {
+			continue
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// directCommits renders the direct commits of the changes for the changelog
+func (r *Releaser) directCommits(changes *Changes) ([]changelogCommit, error) {
+	commits := []changelogCommit{}
+	for _, c := range r.DirectCommits(changes) {
+		subject := strings.SplitN(c.GetCommit().GetMessage(), "\n", 2)[0]
+		for _, l := range r.issueLinks {
+			var err error
+			if subject, err = l.Link(subject); err != nil {
+				return nil, errors.Wrapf(err, "Failed to link issues of commit '%.7s'", c.GetSHA())
+			}
+		}
+		author := c.GetCommit().GetAuthor().GetName()
+		if c.GetAuthor().GetLogin() != "" {
+			author = mention(c.GetAuthor().GetLogin())
+		}
+		commits = append(commits, changelogCommit{
+			SHA:     fmt.Sprintf("%.7s", c.GetSHA()),
+			Subject: strings.TrimSpace(subject),
+			Author:  author,
+			URL:     c.GetHTMLURL(),
+		})
+	}
+	return commits, nil
+}
+
+// AppendDirectCommits adds the direct commits of the changes to release notes
+// generated by GitHub, which only cover pull requests
+func (r *Releaser) AppendDirectCommits(notes string, changes *Changes) (string, error) {
+	commits, err := r.directCommits(changes)
+	if err != nil || len(commits) == 0 {
+		return notes, err
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s\n\n## Direct commits\n\n", strings.TrimRight(notes, "\n"))
+	for _, c := range commits {
+		fmt.Fprintf(b, "* %s by %s in %s\n", c.Subject, c.Author, c.SHA)
+	}
+	return b.String(), nil
+}
+
+// GithubNotes builds the release notes of the github type, for a tag that is to be
+// created at the target commit. GitHub generates notes from every pull request
+// between the tags, so components get notes in the same format built from their
+// own pull requests instead
+func (r *Releaser) GithubNotes(ctx context.Context, changes *Changes, tag, target, previous string) (string, error) {
+	notes := ""
+	if r.component.Name == "" {
+		generated, err := r.client.GenerateReleaseNotes(ctx, tag, target, previous)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to generate release notes")
+		}
+		notes = generated.Body
+	} else {
+		b := &strings.Builder{}
+		b.WriteString("## What's Changed\n")
+		for _, p := range changes.Pulls {
+			fmt.Fprintf(b, "* %s by @%s in %s\n", p.GetTitle(), p.GetUser().GetLogin(), p.GetHTMLURL())
+		}
+		if previous != "" {
+			fmt.Fprintf(b, "\n**Full Changelog**: %s\n", r.compareURL(previous, tag))
+		}
+		notes = b.String()
+	}
+	body, err := r.AppendDirectCommits(notes, changes)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to add direct commits to release notes")
+	}
+	return body, nil
+}
+
+// changelogPull prepares a pull request for the changelog, linking the issues it
+// references
+func (r *Releaser) changelogPull(p *github.PullRequest, issues *[]changelogIssue) (changelogPull, error) {
+	labels := []string{}
+	for _, l := range p.Labels {
+		labels = append(labels, l.GetName())
+	}
+	pull := changelogPull{
+		Number: p.GetNumber(),
+		Title:  p.GetTitle(),
+		Author: p.GetUser().GetLogin(),
+		URL:    p.GetHTMLURL(),
+		Labels: labels,
+		Notes:  releaseNotes(p.GetBody()),
+	}
+	if err := r.linkIssues(&pull, p.GetBody(), issues); err != nil {
+		return pull, errors.Wrapf(err, "Failed to link issues of pull request #%d", p.GetNumber())
+	}
+	texts := []string{}
+	for _, n := range pull.Notes {
+		texts = append(texts, n.Text)
+	}
+	pull.Note = strings.Join(texts, "\n")
+	return pull, nil
+}
+
+// CollectChangelog renders the changelog of a release with the changelog template.
+// The version may be nil when rendering changes not yet released
+func (r *Releaser) CollectChangelog(ctx context.Context, changes *Changes, version *semver.Version, tag, previous string) (string, error) {
+	data := changelogData{
+		Tag:           tag,
+		PreviousTag:   previous,
+		CompareURL:    r.compareURL(previous, tag),
+		Pulls:         []changelogPull{},
+		Issues:        []changelogIssue{},
+		Dependencies:  []changelogDependency{},
+		DirectCommits: []changelogCommit{},
+	}
+	if version != nil {
+		data.Version = r.scheme.Format(version)
+	}
+	for _, p := range changes.Pulls {
+		if r.IsDependency(p) {
+			data.Dependencies = append(data.Dependencies, parseDependency(p.GetNumber(), p.GetTitle()))
+			continue
+		}
+		pull, err := r.changelogPull(p, &data.Issues)
+		if err != nil {
+			return "", err
+		}
+		data.Pulls = append(data.Pulls, pull)
+	}
+	data.Sections = r.sections(data.Pulls)
+	commits, err := r.directCommits(changes)
+	if err != nil {
+		return "", err
+	}
+	data.DirectCommits = commits
+	if r.config.Changelog.Contributors {
+		data.Contributors = r.Contributors(changes)
+		if previous != "" {
+			// Crediting new contributors is not worth failing the release over
+			contributors, err := r.NewContributors(ctx, changes, previous)
+			if err != nil {
+				r.log.WithError(err).Warn("Failed to find new contributors. Leaving them out of the changelog")
+			}
+			data.NewContributors = contributors
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.changelog.Execute(buf, data); err != nil {
+		return "", errors.Wrap(err, "Failed to render changelog template")
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
@@ -0,0 +1,138 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v43/github"
+	"github.com/pkg/errors"
+)
+
+const changelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/).
+`
+
+var (
+	changelogEntryRx          = regexp.MustCompile(`(?m)^## `)
+	changelogUnreleasedRx     = regexp.MustCompile(`(?mi)^## \[?unreleased\]?.*$`)
+	changelogLinkRx           = regexp.MustCompile(`(?m)^\[[^\]]+\]: `)
+	changelogUnreleasedLinkRx = regexp.MustCompile(`(?mi)^\[unreleased\]: .*\n`)
+	// changelogUnreleasedDiffRx matches the previous tag in the link of the Unreleased section
+	changelogUnreleasedDiffRx = regexp.MustCompile(`(?mi)^(\[unreleased\]: .*/compare/)\S+?(\.\.\.\S+)$`)
+)
+
+// changelogEntry renders the notes of a release as a Keep a Changelog entry.
+// Headings in the notes are moved a level down to nest under the entry
+func changelogEntry(version, notes string, date time.Time) string {
+	lines := strings.Split(strings.TrimSpace(notes), "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "#") {
+			lines[i] = "#" + l
+		}
+	}
+	return fmt.Sprintf("## [%s] - %s\n\n%s\n", version, date.Format("2006-01-02"), strings.Join(lines, "\n"))
+}
+
+// prependChangelog adds an entry above the previous entries of a changelog, and
+// a link to the diff of the release among the link definitions at the bottom. An
+// Unreleased section and its link stay at the top, and the link is moved to compare
+// from the tag
+func prependChangelog(content, entry, version, tag, link string) string {
+	if strings.TrimSpace(content) == "" {
+		content = changelogHeader
+	}
+	start := 0
+	if loc := changelogUnreleasedRx.FindStringIndex(content); loc != nil {
+		start = loc[1]
+	}
+	if loc := changelogEntryRx.FindStringIndex(content[start:]); loc != nil {
+		content = content[:start+loc[0]] + entry + "\n" + content[start+loc[0]:]
+	} else if loc := changelogLinkRx.FindStringIndex(content[start:]); loc != nil {
+		content = content[:start+loc[0]] + entry + "\n" + content[start+loc[0]:]
+	} else {
+		content = strings.TrimRight(content, "\n") + "\n\n" + entry
+	}
+	content = changelogUnreleasedDiffRx.ReplaceAllString(content, fmt.Sprintf("${1}%s${2}", strings.ReplaceAll(tag, "$", "$$")))
+	if link == "" {
+		return content
+	}
+	definition := fmt.Sprintf("[%s]: %s\n", version, link)
+	content = strings.TrimRight(content, "\n") + "\n"
+	if loc := changelogUnreleasedLinkRx.FindStringIndex(content); loc != nil {
+		return content[:loc[1]] + definition + content[loc[1]:]
+	}
+	if loc := changelogLinkRx.FindStringIndex(content); loc != nil {
+		return content[:loc[0]] + definition + content[loc[0]:]
+	}
+	return strings.TrimRight(content, "\n") + "\n\n" + definition
+}
+
+// UpdateChangelogFile prepends the notes of a full release to the changelog file
+// on the branch, either by committing directly to the branch or by opening a pull
+// request, as configured in changelog.fileMode
+func (r *Releaser) UpdateChangelogFile(ctx context.Context, branch, tag, version, name, notes, link string) error {
+	path := r.config.Changelog.File
+	head, err := r.client.GetRef(ctx, fmt.Sprintf("heads/%s", branch))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get head of branch '%s'", branch)
+	}
+	sha := head.GetObject().GetSHA()
+
+	content := ""
+	reader, err := r.client.GetFile(ctx, sha, path)
+	if err != nil && !errors.Is(err, ErrFileMissing) {
+		return errors.Wrapf(err, "Failed to get changelog file '%s'", path)
+	}
+	if err == nil {
+		b, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read changelog file '%s'", path)
+		}
+		content = string(b)
+	}
+	content = prependChangelog(content, changelogEntry(version, notes, time.Now()), version, tag, link)
+
+	message := fmt.Sprintf("Update changelog for %s\n\n%s", name, skipDirective)
+	commit, err := r.client.CreateCommit(ctx, sha, message, map[string][]byte{path: []byte(content)})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to commit changelog file '%s'", path)
+	}
+
+	if r.config.Changelog.FileMode == "commit" {
+		r.log.Debugf("Committing changelog to branch '%s'", branch)
+		if err := r.client.UpdateRef(ctx, fmt.Sprintf("heads/%s", branch), commit); err != nil {
+			return errors.Wrapf(err, "Failed to update branch '%s'", branch)
+		}
+		return nil
+	}
+
+	head = &github.Reference{
+		Ref: github.String(fmt.Sprintf("refs/heads/ship-it/changelog/%s", tag)),
+		Object: &github.GitObject{
+			SHA: github.String(commit),
+		},
+	}
+	if err := r.client.CreateRef(ctx, head); err != nil {
+		return errors.Wrapf(err, "Failed to create branch '%s'", head.GetRef())
+	}
+	r.log.Debugf("Opening pull request with changelog to branch '%s'", branch)
+	pull, err := r.client.CreatePull(ctx, &github.NewPullRequest{
+		Title: github.String(fmt.Sprintf("Update changelog for %s", name)),
+		Head:  github.String(strings.TrimPrefix(head.GetRef(), "refs/heads/")),
+		Base:  github.String(branch),
+		Body:  github.String(fmt.Sprintf("Adds the release notes of %s to `%s`.\n\n%s", name, path, skipDirective)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to open pull request")
+	}
+	r.log.Infof("Opened pull request #%d with changelog", pull.GetNumber())
+	return nil
+}
@@ -0,0 +1,143 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v43/github"
+	"github.com/pkg/errors"
+)
+
+// component is an independently versioned part of the repository. A repository
+// without configured components is released as a single unnamed component
+type component struct {
+	Name         string
+	paths        []*regexp.Regexp
+	tagger       *Tagger
+	versionFiles []versionFile
+}
+
+// versionFile is a file in the repository holding the version of a component
+type versionFile struct {
+	Path   string
+	update versionUpdater
+}
+
+func newComponent(config *Config, conf ComponentConf, scheme Scheme) (*component, error) {
+	tagger, err := NewTagger(config.Tag.Format, config.Release.NameFormat, conf.Name, scheme)
+	if err != nil {
+		return nil, err
+	}
+	paths := []*regexp.Regexp{}
+	for _, p := range conf.Paths {
+		rx, err := globRx(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse path '%s' of component '%s'", p, conf.Name)
+		}
+		paths = append(paths, rx)
+	}
+	files := []versionFile{}
+	for _, f := range conf.VersionFiles {
+		update, err := newVersionUpdater(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to configure version files of component '%s'", conf.Name)
+		}
+		files = append(files, versionFile{Path: f.Path, update: update})
+	}
+	return &component{
+		Name:         conf.Name,
+		paths:        paths,
+		tagger:       tagger,
+		versionFiles: files,
+	}, nil
+}
+
+// globRx translates a path glob to a regular expression. `**` matches across
+// directories, and a pattern also matches everything below it
+func globRx(glob string) (*regexp.Regexp, error) {
+	glob = strings.Trim(glob, "/")
+	b := &strings.Builder{}
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return regexp.Compile(fmt.Sprintf("^%s(/.*)?$", b.String()))
+}
+
+// Scoped tells whether the component only owns part of the repository
+func (c *component) Scoped() bool {
+	return len(c.paths) > 0
+}
+
+// Owns tells whether any of the files belong to the component
+func (c *component) Owns(files []string) bool {
+	if !c.Scoped() {
+		return true
+	}
+	for _, f := range files {
+		for _, rx := range c.paths {
+			if rx.MatchString(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forComponent returns a releaser working on the versions of a single component
+func (r *Releaser) forComponent(c *component) *Releaser {
+	cr := *r
+	cr.component = c
+	cr.tagger = c.tagger
+	if c.Name != "" {
+		cr.log = r.log.WithField("component", c.Name)
+	}
+	return &cr
+}
+
+// componentForTag finds the component whose tag format matches the tag
+func (r *Releaser) componentForTag(tag string) *component {
+	for _, c := range r.components {
+		if _, err := c.tagger.Parse(tag); err == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// scope narrows commits down to those touching the component
+func (r *Releaser) scope(ctx context.Context, commits []*github.RepositoryCommit) ([]*github.RepositoryCommit, error) {
+	if !r.component.Scoped() {
+		return commits, nil
+	}
+	scoped := []*github.RepositoryCommit{}
+	for _, c := range commits {
+		files, ok := r.files[c.GetSHA()]
+		if !ok {
+			var err error
+			files, err = r.client.GetCommitFiles(ctx, c.GetSHA())
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to get files of commit '%.7s'", c.GetSHA())
+			}
+			r.files[c.GetSHA()] = files
+		}
+		if r.component.Owns(files) {
+			scoped = append(scoped, c)
+		}
+	}
+	return scoped, nil
+}
@@ -0,0 +1,195 @@
+package scm
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	validator "gopkg.in/go-playground/validator.v9"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	configValidator = validator.New()
+	ErrConfMissing  = errors.New("Missing .ship-it file")
+)
+
+// LabelList is a list of labels, which can be given as a single label in yaml
+type LabelList []string
+
+func (l *LabelList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = LabelList{}
+		if single != "" {
+			*l = LabelList{single}
+		}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Has tells whether the label is in the list
+func (l LabelList) Has(label string) bool {
+	for _, candidate := range l {
+		if candidate == label {
+			return true
+		}
+	}
+	return false
+}
+
+type LabelsConfig struct {
+	Major  LabelList `yaml:"major,omitempty"`
+	Minor  LabelList `yaml:"minor,omitempty"`
+	Patch  LabelList `yaml:"patch,omitempty"`
+	None   LabelList `yaml:"none,omitempty"`
+	Skip   LabelList `yaml:"skip,omitempty"`
+	Stable LabelList `yaml:"stable,omitempty"`
+}
+
+type ChannelConf struct {
+	Name   string `yaml:"name" validate:"required,alphanum"`
+	Branch string `yaml:"branch,omitempty"`
+}
+
+type StrategyConf struct {
+	Type     string        `yaml:"type,omitempty" validate:"oneof=pre-release full-release"`
+	Channels []ChannelConf `yaml:"channels,omitempty" validate:"min=1,dive"`
+}
+
+type SectionConf struct {
+	Title  string    `yaml:"title" validate:"required"`
+	Labels LabelList `yaml:"labels" validate:"min=1"`
+}
+
+type IssueLinkConf struct {
+	Pattern string `yaml:"pattern" validate:"required"`
+	URL     string `yaml:"url" validate:"required"`
+}
+
+type ChangelogConf struct {
+	Type         string          `yaml:"type,omitempty" validate:"oneof=legacy github"`
+	Template     string          `yaml:"template,omitempty"`
+	Sections     []SectionConf   `yaml:"sections,omitempty" validate:"dive"`
+	File         string          `yaml:"file,omitempty"`
+	FileMode     string          `yaml:"fileMode,omitempty" validate:"oneof=commit pull-request"`
+	Contributors bool            `yaml:"contributors,omitempty"`
+	IssueLinks   []IssueLinkConf `yaml:"issueLinks,omitempty" validate:"dive"`
+}
+
+type VersioningConf struct {
+	Source              string `yaml:"source,omitempty" validate:"oneof=labels conventional-commits both"`
+	Scheme              string `yaml:"scheme,omitempty" validate:"oneof=semver calver"`
+	CalVer              string `yaml:"calver,omitempty"`
+	ZeroMajorBumpsMinor bool   `yaml:"zeroMajorBumpsMinor,omitempty"`
+}
+
+type TagConf struct {
+	Format string `yaml:"format,omitempty" validate:"required"`
+}
+
+type ReleaseConf struct {
+	NameFormat string `yaml:"nameFormat,omitempty" validate:"required"`
+}
+
+type BranchConf struct {
+	Name        string `yaml:"name" validate:"required"`
+	Maintenance bool   `yaml:"maintenance,omitempty"`
+}
+
+type VersionFileConf struct {
+	Path    string `yaml:"path" validate:"required"`
+	Type    string `yaml:"type" validate:"oneof=json yaml regex"`
+	Key     string `yaml:"key,omitempty"`
+	Pattern string `yaml:"pattern,omitempty"`
+}
+
+type ComponentConf struct {
+	Name         string            `yaml:"name" validate:"required"`
+	Paths        []string          `yaml:"paths" validate:"min=1"`
+	VersionFiles []VersionFileConf `yaml:"versionFiles,omitempty" validate:"dive"`
+}
+
+type DependenciesConf struct {
+	Authors []string  `yaml:"authors,omitempty"`
+	Labels  LabelList `yaml:"labels,omitempty"`
+}
+
+type PullRequestsConf struct {
+	Check              bool `yaml:"check,omitempty"`
+	RequireReleaseNote bool `yaml:"requireReleaseNote,omitempty"`
+	Comment            bool `yaml:"comment,omitempty"`
+}
+
+type Config struct {
+	TargetBranch   string            `yaml:"targetBranch" validate:"required"`
+	Labels         LabelsConfig      `yaml:"labels,omitempty"`
+	Strategy       StrategyConf      `yaml:"strategy,omitempty"`
+	Changelog      ChangelogConf     `yaml:"changelog,omitempty"`
+	Versioning     VersioningConf    `yaml:"versioning,omitempty"`
+	Tag            TagConf           `yaml:"tag,omitempty"`
+	Release        ReleaseConf       `yaml:"release,omitempty"`
+	Components     []ComponentConf   `yaml:"components,omitempty" validate:"dive"`
+	Branches       []BranchConf      `yaml:"branches,omitempty" validate:"dive"`
+	InitialVersion string            `yaml:"initialVersion,omitempty" validate:"required"`
+	VersionFiles   []VersionFileConf `yaml:"versionFiles,omitempty" validate:"dive"`
+	PullRequests   PullRequestsConf  `yaml:"pullRequests,omitempty"`
+	Dependencies   DependenciesConf  `yaml:"dependencies,omitempty"`
+}
+
+func getConfig(ctx context.Context, c GithubClient, ref string) (*Config, error) {
+	config := &Config{
+		TargetBranch:   c.GetRepo().GetDefaultBranch(),
+		InitialVersion: "0.1.0",
+		Labels: LabelsConfig{
+			Major:  LabelList{"major"},
+			Minor:  LabelList{"minor"},
+			Patch:  LabelList{"patch"},
+			None:   LabelList{},
+			Skip:   LabelList{"no-release"},
+			Stable: LabelList{"stable"},
+		},
+		Strategy: StrategyConf{
+			Type: "pre-release",
+			Channels: []ChannelConf{
+				{Name: "rc"},
+			},
+		},
+		Changelog: ChangelogConf{
+			Type:     "github",
+			FileMode: "commit",
+		},
+		Versioning: VersioningConf{
+			Source: "labels",
+			Scheme: "semver",
+			CalVer: "YYYY.MM.MICRO",
+		},
+		Tag: TagConf{
+			Format: "{{if .Component}}{{.Component}}/{{end}}v{{.Version}}",
+		},
+		Release: ReleaseConf{
+			NameFormat: "{{if .Component}}{{.Component}} {{end}}{{.Version}}",
+		},
+	}
+	reader, err := c.GetFile(ctx, ref, ".ship-it")
+	if err != nil {
+		if errors.Is(err, ErrFileMissing) {
+			return nil, ErrConfMissing
+		}
+		return nil, errors.Wrap(err, "Failed to get .ship-it file for configuration")
+	}
+	defer reader.Close()
+	decoder := yaml.NewDecoder(reader)
+	if err := decoder.Decode(config); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode config file")
+	}
+	if err := configValidator.Struct(config); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate configuration")
+	}
+	return config, nil
+}
@@ -0,0 +1,117 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	coAuthorRx = regexp.MustCompile(`(?mi)^Co-authored-by:[ \t]*(?P<name>[^<\r\n]*?)[ \t]*<(?P<email>[^>\r\n]*)>`)
+	// noreplyRx reads the login from the private emails GitHub hands out
+	noreplyRx = regexp.MustCompile(`^([0-9]+\+)?(?P<login>[^@]+)@users\.noreply\.github\.com$`)
+)
+
+// newContributor is an author whose first pull request is in the release
+type newContributor struct {
+	Mention string
+	Pull    int
+}
+
+func mention(login string) string {
+	return fmt.Sprintf("@%s", login)
+}
+
+func isBot(login string) bool {
+	return strings.HasSuffix(login, "[bot]")
+}
+
+// coAuthors reads the Co-authored-by trailers of a text. Authors with GitHub
+// noreply emails are mentioned by login, others by name
+func coAuthors(text string) []string {
+	authors := []string{}
+	for _, matches := range coAuthorRx.FindAllStringSubmatch(text, -1) {
+		name, email := matches[coAuthorRx.SubexpIndex("name")], matches[coAuthorRx.SubexpIndex("email")]
+		if m := noreplyRx.FindStringSubmatch(email); m != nil {
+			authors = append(authors, mention(m[noreplyRx.SubexpIndex("login")]))
+			continue
+		}
+		if name != "" {
+			authors = append(authors, name)
+		}
+	}
+	return authors
+}
+
+// Contributors lists the authors and co-authors of the changes, leaving out bots
+func (r *Releaser) Contributors(changes *Changes) []string {
+	seen := map[string]bool{}
+	contributors := []string{}
+	add := func(c string) {
+		if isBot(c) || seen[strings.ToLower(c)] {
+			return
+		}
+		seen[strings.ToLower(c)] = true
+		contributors = append(contributors, c)
+	}
+	for _, p := range changes.Pulls {
+		add(mention(p.GetUser().GetLogin()))
+		for _, c := range coAuthors(p.GetBody()) {
+			add(c)
+		}
+	}
+	for _, c := range changes.Commits {
+		for _, a := range coAuthors(c.GetCommit().GetMessage()) {
+			add(a)
+		}
+	}
+	return contributors
+}
+
+// newContributorsLimit bounds the authors looked up by NewContributors, as every
+// lookup is a call to the search API, which allows about 30 calls a minute
+const newContributorsLimit = 20
+
+// NewContributors finds the pull request authors without merged pull requests
+// before the previous release
+func (r *Releaser) NewContributors(ctx context.Context, changes *Changes, previous string) ([]newContributor, error) {
+	ref, err := r.client.GetRef(ctx, fmt.Sprintf("tags/%s", previous))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get reference to tag '%s'", previous)
+	}
+	commit, err := r.client.GetCommit(ctx, ref.GetObject().GetSHA())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get commit of tag '%s'", previous)
+	}
+	before := commit.GetCommitter().GetDate()
+
+	// firstPulls maps each author to their first pull request in the release
+	firstPulls := map[string]int{}
+	authors := []string{}
+	for _, p := range changes.Pulls {
+		login := p.GetUser().GetLogin()
+		if _, ok := firstPulls[login]; isBot(login) || ok {
+			continue
+		}
+		firstPulls[login] = p.GetNumber()
+		authors = append(authors, login)
+	}
+	if len(authors) > newContributorsLimit {
+		return nil, errors.Errorf("%d authors are more than the %d that can be looked up", len(authors), newContributorsLimit)
+	}
+
+	contributors := []newContributor{}
+	for _, login := range authors {
+		merged, err := r.client.CountMergedPulls(ctx, login, before)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to count pull requests of '%s'", login)
+		}
+		if merged == 0 {
+			contributors = append(contributors, newContributor{Mention: mention(login), Pull: firstPulls[login]})
+		}
+	}
+	return contributors, nil
+}
@@ -0,0 +1,81 @@
+package scm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v43/github"
+)
+
+var (
+	// bumpTitleRx matches titles of Dependabot, e.g. "Bump lodash from 4.17.15 to 4.17.21 in /web"
+	bumpTitleRx = regexp.MustCompile(`(?i)\bbump (?P<package>\S+) from (?P<from>\S+) to (?P<to>\S+)`)
+	// updateTitleRx matches titles of Renovate, e.g. "Update dependency lodash to v4.17.21"
+	updateTitleRx = regexp.MustCompile(`(?i)\bupdate (?:dependency |module )?(?P<package>\S+)(?: \S+)? to (?P<to>\S+)`)
+)
+
+// changelogDependency is a dependency update of a dependency bot
+type changelogDependency struct {
+	Pull    int
+	Title   string
+	Package string
+	// From and To are empty when they could not be read from the title
+	From string
+	To   string
+}
+
+// Change describes the update, e.g. "lodash from 4.17.15 to 4.17.21", or gives the
+// title when the update could not be read from it
+func (d changelogDependency) Change() string {
+	if d.Package == "" {
+		return d.Title
+	}
+	if d.From == "" {
+		return fmt.Sprintf("%s to %s", d.Package, d.To)
+	}
+	return fmt.Sprintf("%s from %s to %s", d.Package, d.From, d.To)
+}
+
+// parseDependency reads the package and versions of a dependency update from the
+// title of its pull request
+func parseDependency(number int, title string) changelogDependency {
+	dependency := changelogDependency{Pull: number, Title: title}
+	if matches := bumpTitleRx.FindStringSubmatch(title); matches != nil {
+		dependency.Package = matches[bumpTitleRx.SubexpIndex("package")]
+		dependency.From = matches[bumpTitleRx.SubexpIndex("from")]
+		dependency.To = matches[bumpTitleRx.SubexpIndex("to")]
+	} else if matches := updateTitleRx.FindStringSubmatch(title); matches != nil {
+		dependency.Package = matches[updateTitleRx.SubexpIndex("package")]
+		dependency.To = matches[updateTitleRx.SubexpIndex("to")]
+	}
+	return dependency
+}
+
+// IsDependency tells whether a pull request is a dependency update, by its author
+// or labels as configured in dependencies
+func (r *Releaser) IsDependency(p *github.PullRequest) bool {
+	for _, author := range r.config.Dependencies.Authors {
+		if strings.EqualFold(author, p.GetUser().GetLogin()) {
+			return true
+		}
+	}
+	for _, l := range p.Labels {
+		if r.config.Dependencies.Labels.Has(l.GetName()) {
+			return true
+		}
+	}
+	return false
+}
+
+// levelLimit is the highest level a pull request may request. Dependency updates
+// only bump patch, unless they are explicitly labelled with a higher level
+func (r *Releaser) levelLimit(p *github.PullRequest) Level {
+	if !r.IsDependency(p) {
+		return LevelMajor
+	}
+	if level, _ := r.LabelLevel(p); level > LevelPatch {
+		return level
+	}
+	return LevelPatch
+}
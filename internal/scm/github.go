@@ -2,12 +2,13 @@ package scm
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
+	"time"
 
-	semver "github.com/Masterminds/semver/v3"
 	"github.com/google/go-github/v43/github"
 	"github.com/pkg/errors"
 )
@@ -21,13 +22,24 @@ type GithubClient interface {
 	EditRelease(ctx context.Context, id int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error)
 	GetRef(ctx context.Context, r string) (*github.Reference, error)
 	CreateRef(ctx context.Context, r *github.Reference) error
+	UpdateRef(ctx context.Context, r, sha string) error
 	CreateRelease(ctx context.Context, r *github.RepositoryRelease) error
 	GetRefs(ctx context.Context, pattern string) ([]*github.Reference, error)
 	GetCommitRange(ctx context.Context, base, head string) ([]*github.RepositoryCommit, error)
-	GetPullsInCommitRange(ctx context.Context, commits []*github.RepositoryCommit) ([]*github.PullRequest, error)
-	GetLatestTag(ctx context.Context) (tag string, ver *semver.Version, err error)
+	GetCommits(ctx context.Context, head string, limit int) ([]*github.RepositoryCommit, error)
+	GetPullsByCommit(ctx context.Context, commits []*github.RepositoryCommit) (map[string][]*github.PullRequest, error)
 	GetFile(ctx context.Context, ref, file string) (io.ReadCloser, error)
-	GenerateReleaseNotes(ctx context.Context, curr string) (*github.RepositoryReleaseNotes, error)
+	GetCommitFiles(ctx context.Context, sha string) ([]string, error)
+	CreateCommit(ctx context.Context, parent, message string, files map[string][]byte) (string, error)
+	CreatePull(ctx context.Context, pull *github.NewPullRequest) (*github.PullRequest, error)
+	CreateCheckRun(ctx context.Context, opts github.CreateCheckRunOptions) error
+	GetPullCommits(ctx context.Context, number int) ([]*github.RepositoryCommit, error)
+	GetCommit(ctx context.Context, sha string) (*github.Commit, error)
+	CountMergedPulls(ctx context.Context, author string, before time.Time) (int, error)
+	GetComments(ctx context.Context, number int) ([]*github.IssueComment, error)
+	CreateComment(ctx context.Context, number int, body string) error
+	EditComment(ctx context.Context, id int64, body string) error
+	GenerateReleaseNotes(ctx context.Context, curr, target, previous string) (*github.RepositoryReleaseNotes, error)
 	GetRepo() Repo
 }
 
@@ -36,6 +48,7 @@ type Repo interface {
 	GetOwner() *github.User
 	GetName() string
 	GetDefaultBranch() string
+	GetHTMLURL() string
 }
 
 type GithubClientImpl struct {
@@ -52,10 +65,19 @@ func NewGithubClient(tc *http.Client, repo Repo) *GithubClientImpl {
 	}
 }
 
-func (c *GithubClientImpl) GenerateReleaseNotes(ctx context.Context, curr string) (*github.RepositoryReleaseNotes, error) {
-	notes, _, err := c.client.Repositories.GenerateReleaseNotes(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), &github.GenerateNotesOptions{
+// GenerateReleaseNotes generates notes for a tag, which is created at the target
+// commit when it does not exist yet
+func (c *GithubClientImpl) GenerateReleaseNotes(ctx context.Context, curr, target, previous string) (*github.RepositoryReleaseNotes, error) {
+	opts := &github.GenerateNotesOptions{
 		TagName: curr,
-	})
+	}
+	if target != "" {
+		opts.TargetCommitish = github.String(target)
+	}
+	if previous != "" {
+		opts.PreviousTagName = github.String(previous)
+	}
+	notes, _, err := c.client.Repositories.GenerateReleaseNotes(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), opts)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to generate release notes for tag '%s'", curr)
 	}
@@ -122,6 +144,63 @@ func (c *GithubClientImpl) CreateRef(ctx context.Context, r *github.Reference) e
 	return err
 }
 
+func (c *GithubClientImpl) UpdateRef(ctx context.Context, r, sha string) error {
+	_, _, err := c.client.Git.UpdateRef(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), &github.Reference{
+		Ref: github.String(r),
+		Object: &github.GitObject{
+			SHA: github.String(sha),
+		},
+	}, false)
+	return err
+}
+
+func (c *GithubClientImpl) CreatePull(ctx context.Context, pull *github.NewPullRequest) (*github.PullRequest, error) {
+	p, _, err := c.client.PullRequests.Create(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), pull)
+	return p, err
+}
+
+func (c *GithubClientImpl) CreateCheckRun(ctx context.Context, opts github.CreateCheckRunOptions) error {
+	_, _, err := c.client.Checks.CreateCheckRun(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), opts)
+	return err
+}
+
+func (c *GithubClientImpl) GetPullCommits(ctx context.Context, number int) ([]*github.RepositoryCommit, error) {
+	return c.paginatePullCommits(ctx, number, &github.ListOptions{PerPage: 100})
+}
+
+func (c *GithubClientImpl) GetCommit(ctx context.Context, sha string) (*github.Commit, error) {
+	commit, _, err := c.client.Git.GetCommit(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), sha)
+	return commit, err
+}
+
+// CountMergedPulls counts the pull requests of an author merged before a time
+func (c *GithubClientImpl) CountMergedPulls(ctx context.Context, author string, before time.Time) (int, error) {
+	query := fmt.Sprintf("repo:%s is:pr is:merged author:%s merged:<%s", c.repo.GetFullName(), author, before.UTC().Format(time.RFC3339))
+	result, _, err := c.client.Search.Issues(ctx, query, &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 1}})
+	if err != nil {
+		return 0, err
+	}
+	return result.GetTotal(), nil
+}
+
+func (c *GithubClientImpl) GetComments(ctx context.Context, number int) ([]*github.IssueComment, error) {
+	return c.paginateComments(ctx, number, &github.ListOptions{PerPage: 100})
+}
+
+func (c *GithubClientImpl) CreateComment(ctx context.Context, number int, body string) error {
+	_, _, err := c.client.Issues.CreateComment(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), number, &github.IssueComment{
+		Body: github.String(body),
+	})
+	return err
+}
+
+func (c *GithubClientImpl) EditComment(ctx context.Context, id int64, body string) error {
+	_, _, err := c.client.Issues.EditComment(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), id, &github.IssueComment{
+		Body: github.String(body),
+	})
+	return err
+}
+
 func (c *GithubClientImpl) CreateRelease(ctx context.Context, r *github.RepositoryRelease) error {
 	_, _, err := c.client.Repositories.CreateRelease(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), r)
 	return err
@@ -139,19 +218,43 @@ func (c *GithubClientImpl) GetCommitRange(ctx context.Context, base, head string
 	return commits, nil
 }
 
-func (c *GithubClientImpl) GetPullsInCommitRange(ctx context.Context, commits []*github.RepositoryCommit) ([]*github.PullRequest, error) {
-	max := 500
-	if len(commits) < max {
-		max = len(commits)
+// GetCommits lists the newest commits in the history of head, up to the limit
+func (c *GithubClientImpl) GetCommits(ctx context.Context, head string, limit int) ([]*github.RepositoryCommit, error) {
+	commits, err := c.paginateCommits(ctx, &github.CommitsListOptions{SHA: head, ListOptions: github.ListOptions{PerPage: 100}}, limit)
+	if err != nil {
+		return nil, err
 	}
-	unique := map[int]interface{}{}
-	pulls := []*github.PullRequest{}
-	for _, commit := range commits[:max] {
+	// Order the history oldest first, like commit ranges
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// GetPullsByCommit maps commits to their pull requests. Only the newest 500 of the
+// commits, ordered oldest first, are looked up, and the rest are left out of the map
+func (c *GithubClientImpl) GetPullsByCommit(ctx context.Context, commits []*github.RepositoryCommit) (map[string][]*github.PullRequest, error) {
+	skip := len(commits) - 500
+	if skip < 0 {
+		skip = 0
+	}
+	byCommit := map[string][]*github.PullRequest{}
+	for _, commit := range commits[skip:] {
 		prs, err := c.paginatePullsWithCommit(ctx, commit.GetSHA(), &github.PullRequestListOptions{})
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to paginate pull requests")
 		}
-		for _, p := range prs {
+		byCommit[commit.GetSHA()] = prs
+	}
+	return byCommit, nil
+}
+
+// UniquePulls lists the pull requests of the commits in commit order, without duplicates
+func UniquePulls(commits []*github.RepositoryCommit, byCommit map[string][]*github.PullRequest) []*github.PullRequest {
+	unique := map[int]interface{}{}
+	pulls := []*github.PullRequest{}
+	for _, commit := range commits {
+		for _, p := range byCommit[commit.GetSHA()] {
 			if _, ok := unique[p.GetNumber()]; ok {
 				continue
 			}
@@ -159,7 +262,63 @@ func (c *GithubClientImpl) GetPullsInCommitRange(ctx context.Context, commits []
 			pulls = append(pulls, p)
 		}
 	}
-	return pulls, nil
+	return pulls
+}
+
+func (c *GithubClientImpl) GetCommitFiles(ctx context.Context, sha string) ([]string, error) {
+	commit, _, err := c.client.Repositories.GetCommit(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), sha, &github.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get commit '%s'", sha)
+	}
+	files := []string{}
+	for _, f := range commit.Files {
+		files = append(files, f.GetFilename())
+		if f.GetPreviousFilename() != "" {
+			files = append(files, f.GetPreviousFilename())
+		}
+	}
+	return files, nil
+}
+
+// CreateCommit commits the content of files on top of the parent commit, using
+// the Git Data API. The commit is not added to any branch
+func (c *GithubClientImpl) CreateCommit(ctx context.Context, parent, message string, files map[string][]byte) (string, error) {
+	owner, name := c.repo.GetOwner().GetLogin(), c.repo.GetName()
+	base, _, err := c.client.Git.GetCommit(ctx, owner, name, parent)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get commit '%.7s'", parent)
+	}
+
+	entries := []*github.TreeEntry{}
+	for file, content := range files {
+		blob, _, err := c.client.Git.CreateBlob(ctx, owner, name, &github.Blob{
+			Content:  github.String(base64.StdEncoding.EncodeToString(content)),
+			Encoding: github.String("base64"),
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to create blob for '%s'", file)
+		}
+		entries = append(entries, &github.TreeEntry{
+			Path: github.String(file),
+			Mode: github.String("100644"),
+			Type: github.String("blob"),
+			SHA:  blob.SHA,
+		})
+	}
+	tree, _, err := c.client.Git.CreateTree(ctx, owner, name, base.GetTree().GetSHA(), entries)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create tree")
+	}
+
+	commit, _, err := c.client.Git.CreateCommit(ctx, owner, name, &github.Commit{
+		Message: github.String(message),
+		Tree:    tree,
+		Parents: []*github.Commit{{SHA: github.String(parent)}},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create commit")
+	}
+	return commit.GetSHA(), nil
 }
 
 var ErrFileMissing = errors.New("File missing in repository")
@@ -168,10 +327,12 @@ func (c *GithubClientImpl) GetFile(ctx context.Context, ref, file string) (io.Re
 	r, o, err := c.client.Repositories.DownloadContents(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), file, &github.RepositoryContentGetOptions{
 		Ref: ref,
 	})
-	if o.StatusCode == http.StatusNotFound {
+	if o != nil && o.StatusCode == http.StatusNotFound {
 		return nil, ErrFileMissing
 	}
-	if errors.Is(err, fmt.Errorf("No file named %s found in %s", path.Base(file), path.Dir(file))) {
+	// DownloadContents lists the directory of the file, and reports a missing file
+	// in an existing directory with a plain error
+	if err != nil && err.Error() == fmt.Sprintf("no file named %s found in %s", path.Base(file), path.Dir(file)) {
 		return nil, ErrFileMissing
 	}
 	return r, err
@@ -181,18 +342,6 @@ func (c *GithubClientImpl) GetRepo() Repo {
 	return c.repo
 }
 
-func (c *GithubClientImpl) GetLatestTag(ctx context.Context) (tag string, ver *semver.Version, err error) {
-	release, _, err := c.client.Repositories.GetLatestRelease(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName())
-	if err != nil {
-		return "", nil, errors.Wrap(err, "Failed to get latest release")
-	}
-	version, err := semver.NewVersion(release.GetTagName())
-	if err != nil {
-		return "", nil, errors.Wrap(err, "Failed to parse tag as semver")
-	}
-	return release.GetTagName(), version, nil
-}
-
 func (c *GithubClientImpl) paginatePullsWithCommit(ctx context.Context, sha string, opts *github.PullRequestListOptions) ([]*github.PullRequest, error) {
 	page := 0
 	pulls := []*github.PullRequest{}
@@ -262,3 +411,72 @@ func (c *GithubClientImpl) paginateCommitRange(ctx context.Context, base, head s
 	}
 	return commits, nil
 }
+
+func (c *GithubClientImpl) paginateCommits(ctx context.Context, opts *github.CommitsListOptions, limit int) ([]*github.RepositoryCommit, error) {
+	page := 0
+	commits := []*github.RepositoryCommit{}
+	for {
+		list, out, err := c.client.Repositories.ListCommits(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), &github.CommitsListOptions{
+			SHA: opts.SHA,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: opts.PerPage,
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list commits")
+		}
+		commits = append(commits, list...)
+		if len(commits) >= limit {
+			commits = commits[:limit]
+			break
+		}
+		if out.NextPage == 0 {
+			break
+		}
+		page = out.NextPage
+	}
+	return commits, nil
+}
+
+func (c *GithubClientImpl) paginatePullCommits(ctx context.Context, number int, opts *github.ListOptions) ([]*github.RepositoryCommit, error) {
+	page := 0
+	commits := []*github.RepositoryCommit{}
+	for {
+		list, out, err := c.client.PullRequests.ListCommits(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), number, &github.ListOptions{
+			Page:    page,
+			PerPage: opts.PerPage,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list pull request commits")
+		}
+		commits = append(commits, list...)
+		if out.NextPage == 0 {
+			break
+		}
+		page = out.NextPage
+	}
+	return commits, nil
+}
+
+func (c *GithubClientImpl) paginateComments(ctx context.Context, number int, opts *github.ListOptions) ([]*github.IssueComment, error) {
+	page := 0
+	comments := []*github.IssueComment{}
+	for {
+		list, out, err := c.client.Issues.ListComments(ctx, c.repo.GetOwner().GetLogin(), c.repo.GetName(), number, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: opts.PerPage,
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list comments")
+		}
+		comments = append(comments, list...)
+		if out.NextPage == 0 {
+			break
+		}
+		page = out.NextPage
+	}
+	return comments, nil
+}
@@ -0,0 +1,144 @@
+package scm
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// issueLinker links keys of an issue tracker, e.g. WF-1234, to their issues
+type issueLinker struct {
+	rx  *regexp.Regexp
+	url *template.Template
+}
+
+// changelogIssue is an issue referenced by the pull requests of a release
+type changelogIssue struct {
+	Key   string
+	URL   string
+	Pulls []int
+}
+
+func newIssueLinker(conf IssueLinkConf) (*issueLinker, error) {
+	rx, err := regexp.Compile(conf.Pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse issue pattern '%s'", conf.Pattern)
+	}
+	url, err := template.New("url").Option("missingkey=error").Parse(conf.URL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse issue url '%s'", conf.URL)
+	}
+	return &issueLinker{rx: rx, url: url}, nil
+}
+
+// URL renders the link to the issue of a key
+func (l *issueLinker) URL(key string) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := l.url.Execute(buf, struct{ Key string }{key}); err != nil {
+		return "", errors.Wrapf(err, "Failed to render issue url for '%s'", key)
+	}
+	return buf.String(), nil
+}
+
+// markdownLinkRx matches markdown links and URLs, whose keys are left as they are
+var markdownLinkRx = regexp.MustCompile(`\[[^\]]*\]\([^)]*\)|https?://[^\s)>\]]+`)
+
+// Link turns the keys in a text into markdown links. Keys already in a link or a
+// URL, e.g. one added by another linker, are not linked again
+func (l *issueLinker) Link(text string) (string, error) {
+	b := &strings.Builder{}
+	last := 0
+	for _, loc := range markdownLinkRx.FindAllStringIndex(text, -1) {
+		linked, err := l.link(text[last:loc[0]])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(linked)
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	linked, err := l.link(text[last:])
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(linked)
+	return b.String(), nil
+}
+
+// link turns every key in a text without links into a markdown link
+func (l *issueLinker) link(text string) (string, error) {
+	var err error
+	linked := l.rx.ReplaceAllStringFunc(text, func(key string) string {
+		url, e := l.URL(key)
+		if e != nil {
+			err = e
+			return key
+		}
+		return fmt.Sprintf("[%s](%s)", key, url)
+	})
+	return linked, err
+}
+
+func newIssueLinkers(confs []IssueLinkConf) ([]*issueLinker, error) {
+	linkers := []*issueLinker{}
+	for _, conf := range confs {
+		l, err := newIssueLinker(conf)
+		if err != nil {
+			return nil, err
+		}
+		linkers = append(linkers, l)
+	}
+	return linkers, nil
+}
+
+// linkIssues links the issue keys in the title and notes of a pull request, and
+// records the issues it references, including those only found in its body
+func (r *Releaser) linkIssues(pull *changelogPull, body string, issues *[]changelogIssue) error {
+	for _, l := range r.issueLinks {
+		keys := l.rx.FindAllString(fmt.Sprintf("%s\n%s", pull.Title, body), -1)
+		for _, n := range pull.Notes {
+			keys = append(keys, l.rx.FindAllString(n.Text, -1)...)
+		}
+		for _, key := range keys {
+			if err := addIssue(issues, l, key, pull.Number); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if pull.Title, err = l.Link(pull.Title); err != nil {
+			return err
+		}
+		for i := range pull.Notes {
+			if pull.Notes[i].Text, err = l.Link(pull.Notes[i].Text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func addIssue(issues *[]changelogIssue, l *issueLinker, key string, pull int) error {
+	for i, issue := range *issues {
+		if issue.Key != key {
+			continue
+		}
+		for _, p := range issue.Pulls {
+			if p == pull {
+				return nil
+			}
+		}
+		(*issues)[i].Pulls = append((*issues)[i].Pulls, pull)
+		return nil
+	}
+	url, err := l.URL(key)
+	if err != nil {
+		return err
+	}
+	*issues = append(*issues, changelogIssue{Key: key, URL: url, Pulls: []int{pull}})
+	return nil
+}
@@ -0,0 +1,288 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v43/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	checkName        = "go-ship-it"
+	predictionMarker = "<!-- go-ship-it:prediction -->"
+)
+
+// CheckResult is the outcome of checking a pull request before it is merged
+type CheckResult struct {
+	Problems []string
+	// Skipped tells whether the pull request opts out of releasing
+	Skipped bool
+	// Entry is the changelog entry the pull request results in, if any
+	Entry string
+}
+
+// Prediction is the release a component would get if a pull request was merged
+type Prediction struct {
+	Component string
+	// Tag and Name are empty when no release would be made
+	Tag  string
+	Name string
+	// Reason tells why no release would be made
+	Reason string
+}
+
+// Passed tells whether the pull request has no problems
+func (c *CheckResult) Passed() bool {
+	return len(c.Problems) == 0
+}
+
+// HandlePull checks pull requests against branches released from. The app is the
+// slug of the GitHub app, whose bot user writes the prediction comments
+func (r *Releaser) HandlePull(ctx context.Context, e *github.PullRequestEvent, app string) {
+	p := e.GetPullRequest()
+	if !r.Match(fmt.Sprintf("refs/heads/%s", p.GetBase().GetRef())) {
+		r.log.Debugf("Pull request #%d does not target a release branch. Ignoring", p.GetNumber())
+		return
+	}
+
+	if r.config.PullRequests.Check {
+		r.log.Debugf("Checking pull request #%d", p.GetNumber())
+		if err := r.publishCheck(ctx, p); err != nil {
+			r.log.WithError(err).Errorf("Failed to publish check for pull request #%d", p.GetNumber())
+		}
+	}
+	if r.config.PullRequests.Comment && p.GetState() == "open" {
+		r.log.Debugf("Predicting release of pull request #%d", p.GetNumber())
+		if err := r.publishPrediction(ctx, p, app); err != nil {
+			r.log.WithError(err).Errorf("Failed to publish prediction for pull request #%d", p.GetNumber())
+		}
+	}
+}
+
+// CheckPull validates the release notes, labels and directives of a pull request
+func (r *Releaser) CheckPull(ctx context.Context, p *github.PullRequest) (*CheckResult, error) {
+	skipped := r.pullSkipped(p)
+	result := &CheckResult{Problems: []string{}, Skipped: skipped}
+
+	if r.config.PullRequests.RequireReleaseNote && !skipped && !changelogRx.MatchString(p.GetBody()) {
+		result.Problems = append(result.Problems, "The description has no `release-note` block. Add one, or a block containing `NONE` to leave the pull request out of the changelog")
+	}
+	if level, conflicts := r.LabelLevel(p); len(conflicts) > 0 {
+		result.Problems = append(result.Problems, fmt.Sprintf("The labels `%s` request different bumps. The highest, %s, would be used", strings.Join(conflicts, "`, `"), level))
+	}
+	if _, err := releaseAs(p.GetBody()); err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("The `Release-As` directive is malformed: %s", err))
+	}
+
+	if skipped {
+		return result, nil
+	}
+	entry, err := r.PullEntry(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	result.Entry = entry
+	return result, nil
+}
+
+// PullEntry renders the changelog entry of a single pull request, as the lines it
+// adds to the sections of the changelog
+func (r *Releaser) PullEntry(ctx context.Context, p *github.PullRequest) (string, error) {
+	if r.config.Changelog.Type == "github" {
+		return fmt.Sprintf("* %s by @%s in %s", p.GetTitle(), p.GetUser().GetLogin(), p.GetHTMLURL()), nil
+	}
+	if r.IsDependency(p) {
+		return fmt.Sprintf("### Dependency updates\n\n- #%d %s", p.GetNumber(), parseDependency(p.GetNumber(), p.GetTitle()).Change()), nil
+	}
+	pull, err := r.changelogPull(p, &[]changelogIssue{})
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to render changelog entry of pull request #%d", p.GetNumber())
+	}
+	entry := &strings.Builder{}
+	for _, s := range r.sections([]changelogPull{pull}) {
+		noted := s.Noted()
+		if len(noted) == 0 {
+			continue
+		}
+		if s.Title != "" {
+			fmt.Fprintf(entry, "### %s\n\n", s.Title)
+		}
+		for _, n := range noted {
+			fmt.Fprintf(entry, "- #%d %s\n", n.Number, n.Note)
+		}
+		entry.WriteString("\n")
+	}
+	return strings.TrimRight(entry.String(), "\n"), nil
+}
+
+func (r *Releaser) publishCheck(ctx context.Context, p *github.PullRequest) error {
+	result, err := r.CheckPull(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	conclusion, title := "success", "Ready to release"
+	if !result.Passed() {
+		conclusion, title = "failure", fmt.Sprintf("%d problems found", len(result.Problems))
+		if len(result.Problems) == 1 {
+			title = "1 problem found"
+		}
+	}
+	summary := &strings.Builder{}
+	for _, problem := range result.Problems {
+		fmt.Fprintf(summary, "- %s\n", problem)
+	}
+	if len(result.Problems) > 0 {
+		summary.WriteString("\n")
+	}
+	switch {
+	case result.Skipped:
+		summary.WriteString("This pull request does not trigger a release\n")
+	case result.Entry == "":
+		summary.WriteString("This pull request adds nothing to the changelog\n")
+	default:
+		fmt.Fprintf(summary, "### Changelog entry\n\n%s\n", result.Entry)
+	}
+
+	return r.client.CreateCheckRun(ctx, github.CreateCheckRunOptions{
+		Name:       checkName,
+		HeadSHA:    p.GetHead().GetSHA(),
+		Status:     github.String("completed"),
+		Conclusion: github.String(conclusion),
+		Output: &github.CheckRunOutput{
+			Title:   github.String(title),
+			Summary: github.String(summary.String()),
+		},
+	})
+}
+
+// Predict finds the release the component would get if the pull request was
+// merged, from the changes merged since the latest release and those of the pull
+// request. Returns nil when the pull request does not touch the component
+func (r *Releaser) Predict(ctx context.Context, p *github.PullRequest, target *Target) (*Prediction, error) {
+	commits, err := r.client.GetPullCommits(ctx, p.GetNumber())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get commits of pull request #%d", p.GetNumber())
+	}
+	commits, err = r.scope(ctx, commits)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to find commits touching the component")
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	t, v, err := r.LatestVersion(ctx, target.Line)
+	first := errors.Is(err, ErrNoRelease) && target.Line == nil
+	if err != nil && !first {
+		return nil, errors.Wrap(err, "Failed to get latest release")
+	}
+	var changes *Changes
+	if first {
+		changes, err = r.History(ctx, target.Branch)
+	} else {
+		changes, err = r.Changes(ctx, t, target.Branch)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to collect changes")
+	}
+	// Like a push, merging only releases when the pull request itself releases
+	own := &Changes{
+		Commits:       commits,
+		Pulls:         []*github.PullRequest{p},
+		PullsByCommit: map[string][]*github.PullRequest{},
+	}
+	for _, c := range commits {
+		changes.Commits = append(changes.Commits, c)
+		changes.PullsByCommit[c.GetSHA()] = []*github.PullRequest{p}
+		own.PullsByCommit[c.GetSHA()] = []*github.PullRequest{p}
+	}
+	changes.Pulls = append(changes.Pulls, p)
+	changes = r.DropReverts(changes)
+
+	prediction := &Prediction{Component: r.component.Name}
+	if skip, reason := r.Skip(own); skip {
+		prediction.Reason = reason
+		return prediction, nil
+	}
+	var next *semver.Version
+	if first {
+		next, err = r.Initial(ctx, target)
+	} else {
+		next, err = r.Increment(ctx, v, changes, target)
+	}
+	if errors.Is(err, ErrNoBump) {
+		prediction.Reason = err.Error()
+		return prediction, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to increment version")
+	}
+	if prediction.Tag, err = r.tagger.Tag(next); err != nil {
+		return nil, err
+	}
+	if prediction.Name, err = r.tagger.Name(next); err != nil {
+		return nil, err
+	}
+	return prediction, nil
+}
+
+func (r *Releaser) publishPrediction(ctx context.Context, p *github.PullRequest, app string) error {
+	target, err := r.Target(fmt.Sprintf("refs/heads/%s", p.GetBase().GetRef()))
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve release target")
+	}
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "%s\n### Release preview\n\n", predictionMarker)
+	predicted := 0
+	for _, c := range r.components {
+		prediction, err := r.forComponent(c).Predict(ctx, p, target)
+		if err != nil {
+			return err
+		}
+		if prediction == nil {
+			continue
+		}
+		predicted++
+		subject := "Merging this pull request"
+		if prediction.Component != "" {
+			subject = fmt.Sprintf("For `%s`, merging this pull request", prediction.Component)
+		}
+		if prediction.Tag == "" {
+			fmt.Fprintf(body, "%s does not release a new version: %s\n", subject, prediction.Reason)
+		} else {
+			fmt.Fprintf(body, "%s releases **%s** as `%s`\n", subject, prediction.Name, prediction.Tag)
+		}
+	}
+	if predicted == 0 {
+		body.WriteString("This pull request does not touch any component\n")
+	}
+	if !r.pullSkipped(p) {
+		entry, err := r.PullEntry(ctx, p)
+		if err != nil {
+			return err
+		}
+		if entry != "" {
+			fmt.Fprintf(body, "\n#### Changelog entry\n\n%s\n", entry)
+		}
+	}
+
+	comments, err := r.client.GetComments(ctx, p.GetNumber())
+	if err != nil {
+		return errors.Wrapf(err, "Failed to list comments of pull request #%d", p.GetNumber())
+	}
+	// Only comments of the bot user of the app are edited, as anyone may copy the marker
+	bot := fmt.Sprintf("%s[bot]", app)
+	for _, c := range comments {
+		if c.GetUser().GetType() == "Bot" && c.GetUser().GetLogin() == bot && strings.HasPrefix(c.GetBody(), predictionMarker) {
+			if c.GetBody() == body.String() {
+				return nil
+			}
+			return r.client.EditComment(ctx, c.GetID(), body.String())
+		}
+	}
+	return r.client.CreateComment(ctx, p.GetNumber(), body.String())
+}
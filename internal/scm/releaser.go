@@ -5,91 +5,137 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
-	"strings"
+	"text/template"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/google/go-github/v43/github"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	validator "gopkg.in/go-playground/validator.v9"
-	"gopkg.in/yaml.v2"
 )
 
 type Releaser struct {
-	client GithubClient
-	config *Config
-	log    *logrus.Entry
+	client     GithubClient
+	config     *Config
+	components []*component
+	component  *component
+	tagger     *Tagger
+	scheme     Scheme
+	initial    *semver.Version
+	changelog  *template.Template
+	issueLinks []*issueLinker
+	files      map[string][]string
+	log        *logrus.Entry
 }
 
 var (
-	configValidator = validator.New()
-	candidateRx     = regexp.MustCompile("^rc.(?P<candidate>[0-9]+)$")
-	changelogRx     = regexp.MustCompile("```release-note([\\s\\S]*?)```")
-	ErrConfMissing  = errors.New("Missing .ship-it file")
+	candidateRx = regexp.MustCompile(`^(?P<channel>[0-9A-Za-z]+)\.(?P<candidate>[0-9]+)$`)
+
+	ErrNoRelease = errors.New("No release found")
 )
 
-type LabelsConfig struct {
-	Major string `yaml:"major,omitempty"`
-	Minor string `yaml:"minor,omitempty"`
+func NewReleaser(ctx context.Context, client GithubClient, ref string, log *logrus.Entry) (*Releaser, error) {
+	config, err := getConfig(ctx, client, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure releaser")
+	}
+	scheme, err := newScheme(config.Versioning)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure versioning scheme")
+	}
+	initial, err := semver.NewVersion(config.InitialVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse initial version '%s'", config.InitialVersion)
+	}
+	changelog, err := loadChangelogTemplate(ctx, client, ref, config.Changelog.Template)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure changelog template")
+	}
+	issueLinks, err := newIssueLinkers(config.Changelog.IssueLinks)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure issue links")
+	}
+	if len(config.Components) > 0 && len(config.VersionFiles) > 0 {
+		return nil, errors.New("The top level versionFiles are not used with components. Configure versionFiles of each component instead")
+	}
+	components := []*component{}
+	confs := config.Components
+	if len(confs) == 0 {
+		confs = []ComponentConf{{VersionFiles: config.VersionFiles}}
+	}
+	// Components must tell their tags apart, or they would release the same tags
+	patterns := map[string]string{}
+	for _, conf := range confs {
+		c, err := newComponent(config, conf, scheme)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to configure component")
+		}
+		if other, ok := patterns[c.tagger.rx.String()]; ok {
+			return nil, errors.Errorf("Components '%s' and '%s' share tags. Tag format '%s' must contain {{.Component}}", other, c.Name, config.Tag.Format)
+		}
+		patterns[c.tagger.rx.String()] = c.Name
+		components = append(components, c)
+	}
+	return &Releaser{
+		client:     client,
+		config:     config,
+		components: components,
+		component:  components[0],
+		tagger:     components[0].tagger,
+		scheme:     scheme,
+		initial:    initial,
+		changelog:  changelog,
+		issueLinks: issueLinks,
+		files:      map[string][]string{},
+		log:        log,
+	}, nil
 }
 
-type StrategyConf struct {
-	Type string `yaml:"type,omitempty" validate:"oneof=pre-release full-release"`
-}
+// Changes collects the commits and pull requests of the component between two refs
+func (r *Releaser) Changes(ctx context.Context, base, head string) (*Changes, error) {
+	r.log.Debugf("Finding commits in range %s..%.7s", base, head)
+	commits, err := r.client.GetCommitRange(ctx, base, head)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get commit range")
+	}
 
-type ChangelogConf struct {
-	Type string `yaml:"type,omitempty" validate:"oneof=legacy github"`
+	return r.collect(ctx, commits)
 }
 
-type Config struct {
-	TargetBranch string        `yaml:"targetBranch" validate:"required"`
-	Labels       LabelsConfig  `yaml:"labels,omitempty"`
-	Strategy     StrategyConf  `yaml:"strategy,omitempty"`
-	Changelog    ChangelogConf `yaml:"changelog,omitempty"`
-}
+// historyLimit bounds the commits read from the history of a ref, newest first, so
+// a first release does not look up files and pull requests of every commit
+const historyLimit = 500
 
-func getConfig(ctx context.Context, c GithubClient, ref string) (*Config, error) {
-	config := &Config{
-		TargetBranch: c.GetRepo().GetDefaultBranch(),
-		Labels: LabelsConfig{
-			Major: "major",
-			Minor: "minor",
-		},
-		Strategy: StrategyConf{
-			Type: "pre-release",
-		},
-		Changelog: ChangelogConf{
-			Type: "github",
-		},
-	}
-	reader, err := c.GetFile(ctx, ref, ".ship-it")
+// History collects the commits and pull requests of the component in the history
+// of a ref, limited to the newest commits
+func (r *Releaser) History(ctx context.Context, head string) (*Changes, error) {
+	r.log.Debugf("Finding commits in the history of %.7s", head)
+	commits, err := r.client.GetCommits(ctx, head, historyLimit)
 	if err != nil {
-		if errors.Is(err, ErrFileMissing) {
-			return nil, ErrConfMissing
-		}
-		return nil, errors.Wrap(err, "Failed to get .ship-it file for configuration")
+		return nil, errors.Wrap(err, "Failed to get commit history")
 	}
-	defer reader.Close()
-	decoder := yaml.NewDecoder(reader)
-	if err := decoder.Decode(config); err != nil {
-		return nil, errors.Wrap(err, "Failed to decode config file")
+	if len(commits) == historyLimit {
+		r.log.Infof("Using the newest %d commits in the history of %.7s", historyLimit, head)
 	}
-	if err := configValidator.Struct(config); err != nil {
-		return nil, errors.Wrap(err, "Failed to validate configuration")
-	}
-	return config, nil
+
+	return r.collect(ctx, commits)
 }
 
-func NewReleaser(ctx context.Context, client GithubClient, ref string, log *logrus.Entry) (*Releaser, error) {
-	config, err := getConfig(ctx, client, ref)
+func (r *Releaser) collect(ctx context.Context, commits []*github.RepositoryCommit) (*Changes, error) {
+	commits, err := r.scope(ctx, commits)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to configure releaser")
+		return nil, errors.Wrap(err, "Failed to find commits touching the component")
 	}
-	return &Releaser{
-		client: client,
-		config: config,
-		log:    log,
-	}, nil
+
+	r.log.Debugf("Finding PRs in %d commits", len(commits))
+	byCommit, err := r.client.GetPullsByCommit(ctx, commits)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get pull requests in commit range")
+	}
+	return r.DropReverts(&Changes{
+		Commits:       commits,
+		Pulls:         UniquePulls(commits, byCommit),
+		PullsByCommit: byCommit,
+	}), nil
 }
 
 func (r *Releaser) HandlePush(ctx context.Context, e *github.PushEvent) {
@@ -97,39 +143,99 @@ func (r *Releaser) HandlePush(ctx context.Context, e *github.PushEvent) {
 		return
 	}
 
-	r.log.Infof("%s pushed. Releasing...", e.GetRef())
-	t, v, err := r.client.GetLatestTag(ctx)
+	target, err := r.Target(e.GetRef())
 	if err != nil {
+		r.log.WithError(err).Error("Failed to resolve release target")
+		return
+	}
+
+	r.log.Infof("%s pushed. Releasing...", e.GetRef())
+	for _, c := range r.components {
+		r.forComponent(c).handlePush(ctx, e, target)
+	}
+}
+
+func (r *Releaser) handlePush(ctx context.Context, e *github.PushEvent, target *Target) {
+	t, v, err := r.LatestVersion(ctx, target.Line)
+	first := errors.Is(err, ErrNoRelease) && target.Line == nil
+	if err != nil && !first {
 		r.log.WithError(err).Error("Failed to get latest release")
 		return
 	}
 
-	r.log.Debugf("Finding commits in range %s..%.7s", t, e.GetAfter())
-	comparison, err := r.client.GetCommitRange(ctx, t, e.GetAfter())
+	var changes *Changes
+	if first {
+		r.log.Info("No previous release found. Releasing the initial version from the full history")
+		changes, err = r.History(ctx, e.GetAfter())
+	} else {
+		changes, err = r.Changes(ctx, t, e.GetAfter())
+	}
 	if err != nil {
-		r.log.WithError(err).Error("Failed to get commit range")
+		r.log.WithError(err).Error("Failed to collect changes")
 		return
 	}
-
-	r.log.Debugf("Finding PRs in %d commits", len(comparison))
-	pulls, err := r.client.GetPullsInCommitRange(ctx, comparison)
+	// Changes cancelled out by reverts still differ from the latest candidate
+	if len(changes.Commits) == 0 && changes.Reverted == 0 {
+		if first {
+			r.log.Info("No changes in the history. Skipping release")
+		} else {
+			r.log.Infof("No changes since '%s'. Skipping release", t)
+		}
+		return
+	}
+	pushed, err := r.pushed(ctx, e, changes)
 	if err != nil {
-		r.log.WithError(err).Error("Failed to get pull requests in commit range")
+		r.log.WithError(err).Error("Failed to collect pushed changes")
+		return
+	}
+	if len(pushed.Commits) == 0 {
+		r.log.Infof("No changes in push %.7s..%.7s. Skipping release", e.GetBefore(), e.GetAfter())
+		return
+	}
+	if skip, reason := r.Skip(pushed); skip {
+		r.log.Infof("Skipping release: %s", reason)
+		return
+	} else if reason != "" {
+		r.log.Debugf("Releasing: %s", reason)
+	}
+	pulls := changes.Pulls
+
+	var next *semver.Version
+	if first {
+		next, err = r.Initial(ctx, target)
+	} else {
+		r.log.Debugf("Finding next version based on %d PRs and %d commits", len(pulls), len(changes.Commits))
+		next, err = r.Increment(ctx, v, changes, target)
+	}
+	if errors.Is(err, ErrNoBump) && changes.Reverted > 0 && r.config.Strategy.Type == "pre-release" {
+		// The latest candidate may contain the reverted changes, so a candidate
+		// without them is released
+		r.log.Infof("%d commits were cancelled out by reverts. Releasing a patch candidate", changes.Reverted)
+		next, err = r.nextCandidate(ctx, r.scheme.Next(v, LevelPatch), target.Channel)
+	}
+	if errors.Is(err, ErrNoBump) {
+		r.log.Infof("Skipping release: %s", err)
 		return
 	}
-
-	r.log.Debugf("Finding next version based on %d PRs", len(pulls))
-	next, err := r.Increment(ctx, v, pulls)
 	if err != nil {
 		r.log.WithError(err).Error("Failed to increment version")
 		return
 	}
-	tagname, name := fmt.Sprintf("v%s", next.String()), next.String()
+	tagname, err := r.tagger.Tag(next)
+	if err != nil {
+		r.log.WithError(err).Error("Failed to render tag")
+		return
+	}
+	name, err := r.tagger.Name(next)
+	if err != nil {
+		r.log.WithError(err).Error("Failed to render release name")
+		return
+	}
 
 	var changelog *string = nil
 	if r.config.Changelog.Type == "legacy" {
 		r.log.Debugf("Collecting changelog from %d PRs", len(pulls))
-		body, err := r.CollectChangelog(pulls)
+		body, err := r.CollectChangelog(ctx, changes, next, tagname, t)
 		if err != nil {
 			r.log.WithError(err).Error("Failed to collect changelog")
 			return
@@ -137,11 +243,32 @@ func (r *Releaser) HandlePush(ctx context.Context, e *github.PushEvent) {
 		changelog = &body
 	}
 
-	r.log.Debugf("Creating tag '%s' at '%.7s'", tagname, e.GetAfter())
+	sha := e.GetAfter()
+	if len(r.component.versionFiles) > 0 {
+		r.log.Debugf("Updating %d version files", len(r.component.versionFiles))
+		sha, err = r.BumpFiles(ctx, sha, tagname, next)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to update version files")
+			return
+		}
+	}
+
+	// Notes are built before tagging, so a failure does not leave a tag behind
+	if r.config.Changelog.Type == "github" {
+		r.log.Debugf("Generating release notes for '%s' since '%s'", tagname, t)
+		body, err := r.GithubNotes(ctx, changes, tagname, sha, t)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to build release notes")
+			return
+		}
+		changelog = &body
+	}
+
+	r.log.Debugf("Creating tag '%s' at '%.7s'", tagname, sha)
 	err = r.client.CreateRef(ctx, &github.Reference{
 		Ref: github.String(fmt.Sprintf("refs/tags/%s", tagname)),
 		Object: &github.GitObject{
-			SHA: github.String(e.GetAfter()),
+			SHA: github.String(sha),
 		},
 	})
 	if err != nil {
@@ -152,29 +279,52 @@ func (r *Releaser) HandlePush(ctx context.Context, e *github.PushEvent) {
 	r.log.WithFields(logrus.Fields{
 		"Name":       name,
 		"TagName":    tagname,
-		"Commitish":  strings.TrimPrefix(e.GetRef(), "refs/heads/"),
+		"Commitish":  target.Branch,
 		"Prerelease": r.config.Strategy.Type == "pre-release",
 	}).Debugf("Creating release")
 	err = r.client.CreateRelease(ctx, &github.RepositoryRelease{
-		TagName:              github.String(tagname),
-		Name:                 github.String(name),
-		TargetCommitish:      github.String(strings.TrimPrefix(e.GetRef(), "refs/heads/")),
-		Prerelease:           github.Bool(r.config.Strategy.Type == "pre-release"),
-		Body:                 changelog,
-		GenerateReleaseNotes: github.Bool(r.config.Changelog.Type == "github"),
+		TagName:         github.String(tagname),
+		Name:            github.String(name),
+		TargetCommitish: github.String(target.Branch),
+		Prerelease:      github.Bool(r.config.Strategy.Type == "pre-release"),
+		Body:            changelog,
 	})
 	if err != nil {
 		r.log.WithError(err).Error("Failed to create release")
+		// Versions are read from tags, so the tag would pass for a release
+		if err := r.client.DeleteTag(ctx, tagname); err != nil {
+			r.log.WithError(err).Errorf("Failed to delete tag '%s'", tagname)
+		}
 		return
 	}
 	r.log.Infof("Release %s created", tagname)
+
+	if r.config.Changelog.File != "" && r.config.Strategy.Type == "full-release" {
+		r.log.Debugf("Adding release notes of '%s' to '%s'", tagname, r.config.Changelog.File)
+		notes := ""
+		if changelog != nil {
+			notes = *changelog
+		}
+		if err := r.UpdateChangelogFile(ctx, target.Branch, tagname, r.scheme.Format(next), name, notes, r.compareURL(t, tagname)); err != nil {
+			r.log.WithError(err).Error("Failed to update changelog file")
+		}
+	}
 }
 
 func (r *Releaser) HandleRelease(ctx context.Context, e *github.ReleaseEvent) {
 	if r.config.Strategy.Type == "full-release" {
 		return
 	}
-	version, err := semver.NewVersion(e.GetRelease().GetTagName())
+	c := r.componentForTag(e.GetRelease().GetTagName())
+	if c == nil {
+		r.log.Debugf("Tag '%s' does not match the tag format. Ignoring release", e.GetRelease().GetTagName())
+		return
+	}
+	r.forComponent(c).handleRelease(ctx, e)
+}
+
+func (r *Releaser) handleRelease(ctx context.Context, e *github.ReleaseEvent) {
+	version, err := r.tagger.Parse(e.GetRelease().GetTagName())
 	if err != nil {
 		r.log.WithError(err).Errorf("Failed to parse tag '%s' as version", e.GetRelease().GetTagName())
 		return
@@ -189,32 +339,56 @@ func (r *Releaser) HandleRelease(ctx context.Context, e *github.ReleaseEvent) {
 		}
 		r.log.Infof("Release promoted to '%s'", n.GetTagName())
 
-		r.log.Info("Adding pull requests to milestone")
-		current, err := semver.NewVersion(n.GetTagName())
+		if n.GetPrerelease() {
+			r.log.Infof("Cleaning up earlier channels of '%s'", n.GetTagName())
+			number, err := r.CleanupCandidates(ctx, n)
+			if err != nil {
+				r.log.WithError(err).Errorf("Failed to clean up candidates for release '%d'", n.GetID())
+				return
+			}
+			r.log.Infof("Removed %d release candidates", number)
+			return
+		}
+
+		current, err := r.tagger.Parse(n.GetTagName())
 		if err != nil {
 			r.log.WithError(err).Errorf("Failed to parse tag '%s' as version", n.GetTagName())
 			return
 		}
 		r.log.Debugf("Finding previous release based on '%s'", current.String())
-		previous, err := r.FindPreviousRelease(ctx, current)
-		if err != nil {
-			r.log.WithError(err).Errorf("Failed to find previous release based on '%s'", current.String())
-			return
+		previous, previousErr := r.FindPreviousTag(ctx, current)
+		first := errors.Is(previousErr, ErrNoRelease)
+		if previousErr != nil && !first {
+			r.log.WithError(previousErr).Warnf("Failed to find previous release based on '%s'", current.String())
 		}
 
-		r.log.Debugf("Finding commits in range %s..%s", previous.GetTagName(), n.GetTagName())
-		comparison, err := r.client.GetCommitRange(ctx, previous.GetTagName(), n.GetTagName())
-		if err != nil {
-			r.log.WithError(err).Error("Failed to get commit range")
-			return
+		if r.config.Changelog.File != "" {
+			branch := n.GetTargetCommitish()
+			if branch == "" {
+				branch = r.config.TargetBranch
+			}
+			r.log.Debugf("Adding release notes of '%s' to '%s'", n.GetTagName(), r.config.Changelog.File)
+			err := r.UpdateChangelogFile(ctx, branch, n.GetTagName(), r.scheme.Format(current), n.GetName(), n.GetBody(), r.compareURL(previous, n.GetTagName()))
+			if err != nil {
+				r.log.WithError(err).Error("Failed to update changelog file")
+			}
 		}
 
-		r.log.Debugf("Finding PRs in %d commits", len(comparison))
-		pulls, err := r.client.GetPullsInCommitRange(ctx, comparison)
+		if previousErr != nil && !first {
+			return
+		}
+		r.log.Info("Adding pull requests to milestone")
+		var changes *Changes
+		if first {
+			changes, err = r.History(ctx, n.GetTagName())
+		} else {
+			changes, err = r.Changes(ctx, previous, n.GetTagName())
+		}
 		if err != nil {
-			r.log.WithError(err).Error("Failed to get pull requests in commit range")
+			r.log.WithError(err).Error("Failed to collect changes")
 			return
 		}
+		pulls := changes.Pulls
 
 		r.log.Debugf("Creating milestone '%s'", n.GetName())
 		milestone, err := r.client.CreateMilestone(ctx, n.GetName())
@@ -248,44 +422,67 @@ func (r *Releaser) HandleRelease(ctx context.Context, e *github.ReleaseEvent) {
 	}
 }
 
-func (r *Releaser) FindPreviousRelease(ctx context.Context, version *semver.Version) (*github.RepositoryRelease, error) {
-	constraint, err := semver.NewConstraint(fmt.Sprintf("<%s", version.String()))
+// LatestVersion finds the highest full release among the tags of the component,
+// restricted to the line of a maintenance branch when given one
+func (r *Releaser) LatestVersion(ctx context.Context, line *Line) (tag string, ver *semver.Version, err error) {
+	versions, err := r.versions(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "Could not create semver constraint")
+		return "", nil, err
+	}
+	var top *taggedVersion
+	for i, v := range versions {
+		if v.Version.Prerelease() != "" {
+			continue
+		}
+		if line != nil && !line.Constraint.Check(v.Version) {
+			continue
+		}
+		if top == nil || v.Version.GreaterThan(top.Version) {
+			top = &versions[i]
+		}
 	}
-	pattern := fmt.Sprintf("v%d.%d.", version.Major(), version.Minor())
-	if version.Patch() == 0 {
-		pattern = fmt.Sprintf("v%d.", version.Major())
+	if top == nil && line != nil {
+		return "", nil, errors.Wrapf(ErrNoRelease, "No release in line '%s' with tag prefix '%s'", line.Name, r.tagger.Prefix())
 	}
-	if version.Minor() == 0 {
-		pattern = "v"
+	if top == nil {
+		return "", nil, errors.Wrapf(ErrNoRelease, "No release with tag prefix '%s'", r.tagger.Prefix())
 	}
+	return top.Tag, top.Version, nil
+}
 
-	refs, err := r.client.GetRefs(ctx, fmt.Sprintf("tags/%s", pattern))
+// FindPreviousTag finds the tag of the highest full release before the version.
+// Tags without a release count too, like when looking up the latest version
+func (r *Releaser) FindPreviousTag(ctx context.Context, version *semver.Version) (string, error) {
+	constraint, err := semver.NewConstraint(fmt.Sprintf("<%s", version.String()))
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to list references with pattern 'tags/%s'", pattern)
+		return "", errors.Wrap(err, "Could not create semver constraint")
 	}
-	top := semver.MustParse("v0.0.0")
-	for _, ref := range refs {
-		tag := strings.TrimPrefix(ref.GetRef(), "refs/tags/")
-		v, err := semver.NewVersion(tag)
-		if err != nil {
-			continue
-		}
-		if !constraint.Check(v) {
+
+	versions, err := r.versions(ctx)
+	if err != nil {
+		return "", err
+	}
+	var top *taggedVersion
+	for i, v := range versions {
+		if !constraint.Check(v.Version) {
 			continue
 		}
-		if v.GreaterThan(top) {
-			top = v
+		if top == nil || v.Version.GreaterThan(top.Version) {
+			top = &versions[i]
 		}
 	}
-	return r.client.GetReleaseByTag(ctx, top.Original())
+	if top == nil {
+		return "", errors.Wrapf(ErrNoRelease, "No release found before '%s'", version.String())
+	}
+	return top.Tag, nil
 }
 
+// Promote moves a pre-release to the next channel, or to a full release when it
+// is in the last channel
 func (r *Releaser) Promote(ctx context.Context, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
-	version, err := semver.NewVersion(release.GetTagName())
+	version, err := r.tagger.Parse(release.GetTagName())
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to parse tag '%s' as semantic version", release.GetTagName())
+		return nil, err
 	}
 
 	full, err := version.SetPrerelease("")
@@ -293,50 +490,134 @@ func (r *Releaser) Promote(ctx context.Context, release *github.RepositoryReleas
 		return nil, errors.Wrapf(err, "Failed to unset prerelease for tag '%s'", release.GetTagName())
 	}
 
+	next := &full
+	channels := r.config.Strategy.Channels
+	if i := r.channelIndex(version); i >= 0 && i < len(channels)-1 {
+		next, err = r.nextCandidate(ctx, full, channels[i+1].Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to find next candidate in channel '%s'", channels[i+1].Name)
+		}
+	}
+
+	tagname, err := r.tagger.Tag(next)
+	if err != nil {
+		return nil, err
+	}
+	name, err := r.tagger.Name(next)
+	if err != nil {
+		return nil, err
+	}
+
 	ref, err := r.client.GetRef(ctx, fmt.Sprintf("tags/%s", release.GetTagName()))
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to get reference to tag '%s'", release.GetTagName())
 	}
+	sha := ref.GetObject().GetSHA()
+	if len(r.component.versionFiles) > 0 {
+		sha, err = r.BumpFiles(ctx, sha, tagname, next)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to update version files for '%s'", tagname)
+		}
+	}
+
+	var changelog *string = nil
+	if next.Prerelease() == "" {
+		previous, err := r.FindPreviousTag(ctx, next)
+		if err != nil && !errors.Is(err, ErrNoRelease) {
+			return nil, errors.Wrapf(err, "Failed to find previous release based on '%s'", next.String())
+		}
+		// Notes cover every change since the previous full release, not only those
+		// of the promoted candidate
+		var changes *Changes
+		if previous == "" {
+			changes, err = r.History(ctx, release.GetTagName())
+		} else {
+			changes, err = r.Changes(ctx, previous, release.GetTagName())
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to collect changes for '%s'", tagname)
+		}
+		switch r.config.Changelog.Type {
+		case "github":
+			body, err := r.GithubNotes(ctx, changes, tagname, sha, previous)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to build release notes for '%s'", tagname)
+			}
+			changelog = &body
+		case "legacy":
+			body, err := r.CollectChangelog(ctx, changes, next, tagname, previous)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to collect changelog for '%s'", tagname)
+			}
+			changelog = &body
+		}
+	}
 
 	err = r.client.CreateRef(ctx, &github.Reference{
-		Ref: github.String(fmt.Sprintf("tags/v%s", full.String())),
+		Ref: github.String(fmt.Sprintf("tags/%s", tagname)),
 		Object: &github.GitObject{
-			SHA: github.String(ref.GetObject().GetSHA()),
+			SHA: github.String(sha),
 		},
 	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to create reference '%s'", full.String())
-	}
-
-	var changelog *string = nil
-	if r.config.Changelog.Type == "github" {
-		notes, err := r.client.GenerateReleaseNotes(ctx, fmt.Sprintf("v%s", full.String()))
-		if err != nil {
-			return nil, errors.Wrapf(err, "Failed to generate release notes for '%s'", full.String())
-		}
-		changelog = &notes.Body
+		return nil, errors.Wrapf(err, "Failed to create reference '%s'", tagname)
 	}
 
 	rel, err := r.client.EditRelease(ctx, release.GetID(), &github.RepositoryRelease{
-		TagName: github.String(fmt.Sprintf("v%s", full.String())),
-		Name:    github.String(full.String()),
-		Body:    changelog,
+		TagName:    github.String(tagname),
+		Name:       github.String(name),
+		Body:       changelog,
+		Prerelease: github.Bool(next.Prerelease() != ""),
 	})
 	if err != nil {
+		if err := r.client.DeleteTag(ctx, tagname); err != nil {
+			r.log.WithError(err).Errorf("Failed to delete tag '%s'", tagname)
+		}
 		return nil, errors.Wrapf(err, "Failed to edit release '%d'", release.GetID())
 	}
 	return rel, nil
 }
 
+// CleanupCandidates removes the candidates of a release. Full releases remove the
+// candidates of every channel, while pre-releases remove those of earlier channels
 func (r *Releaser) CleanupCandidates(ctx context.Context, release *github.RepositoryRelease) (int, error) {
-	refs, err := r.client.GetRefs(ctx, fmt.Sprintf("tags/%s-rc.", release.GetTagName()))
+	version, err := r.tagger.Parse(release.GetTagName())
+	if err != nil {
+		return 0, err
+	}
+	full, err := version.SetPrerelease("")
 	if err != nil {
-		return 0, errors.Wrapf(err, "Failed to list refs for tag '%s'", release.GetTagName())
+		return 0, errors.Wrapf(err, "Failed to unset prerelease for tag '%s'", release.GetTagName())
 	}
 
-	for _, ref := range refs {
-		tag := strings.TrimPrefix(ref.GetRef(), "refs/tags/")
-		if doomed, _ := r.client.GetReleaseByTag(ctx, tag); doomed != nil {
+	channels := r.config.Strategy.Channels
+	if version.Prerelease() != "" {
+		i := r.channelIndex(version)
+		if i < 0 {
+			return 0, nil
+		}
+		channels = channels[:i]
+	}
+	doomedChannels := map[string]bool{}
+	for _, c := range channels {
+		doomedChannels[c.Name] = true
+	}
+
+	versions, err := r.versions(ctx)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to list candidates of '%s'", release.GetTagName())
+	}
+
+	removed := 0
+	for _, v := range versions {
+		result := candidateRx.FindStringSubmatch(v.Version.Prerelease())
+		if result == nil || !doomedChannels[result[candidateRx.SubexpIndex("channel")]] {
+			continue
+		}
+		if core, _ := v.Version.SetPrerelease(""); !core.Equal(&full) {
+			continue
+		}
+		if doomed, _ := r.client.GetReleaseByTag(ctx, v.Tag); doomed != nil {
 			if doomed.GetID() == release.GetID() || !doomed.GetPrerelease() {
 				continue
 			}
@@ -344,47 +625,104 @@ func (r *Releaser) CleanupCandidates(ctx context.Context, release *github.Reposi
 				r.log.WithError(err).Warnf("Failed to delete release '%d'. Continuing...", doomed.GetID())
 			}
 		}
-		if err := r.client.DeleteTag(ctx, tag); err != nil {
-			r.log.WithError(err).Warnf("Failed to delete tag '%s'. Continuing...", tag)
+		if err := r.client.DeleteTag(ctx, v.Tag); err != nil {
+			r.log.WithError(err).Warnf("Failed to delete tag '%s'. Continuing...", v.Tag)
 		}
+		removed++
 	}
 
-	return len(refs), nil
+	return removed, nil
 }
 
-func (r *Releaser) Match(ref string) bool {
-	return strings.TrimPrefix(ref, "refs/heads/") == r.config.TargetBranch
+func (r *Releaser) channelIndex(version *semver.Version) int {
+	result := candidateRx.FindStringSubmatch(version.Prerelease())
+	if result == nil {
+		return -1
+	}
+	for i, c := range r.config.Strategy.Channels {
+		if c.Name == result[candidateRx.SubexpIndex("channel")] {
+			return i
+		}
+	}
+	return -1
 }
 
-func (r *Releaser) Increment(ctx context.Context, current *semver.Version, pulls []*github.PullRequest) (*semver.Version, error) {
-	next := current.IncPatch()
-out:
-	for _, p := range pulls {
-		for _, l := range p.Labels {
-			switch l.GetName() {
-			case r.config.Labels.Minor:
-				next = current.IncMinor()
-			case r.config.Labels.Major:
-				next = current.IncMajor()
-
-				break out
-			}
+// Initial finds the version of the first release of the component
+func (r *Releaser) Initial(ctx context.Context, target *Target) (*semver.Version, error) {
+	initial := r.scheme.Initial(r.initial)
+	if r.config.Strategy.Type == "full-release" {
+		return &initial, nil
+	}
+	return r.nextCandidate(ctx, initial, target.Channel)
+}
+
+func (r *Releaser) Increment(ctx context.Context, current *semver.Version, changes *Changes, target *Target) (*semver.Version, error) {
+	forced, err := r.ReleaseAs(changes, current)
+	if err != nil {
+		return nil, err
+	}
+	if forced != nil {
+		if target.Line != nil && !target.Line.Constraint.Check(forced) {
+			return nil, errors.Errorf("Release-As version '%s' is outside line '%s'", forced.String(), target.Line.Name)
+		}
+		r.log.Infof("Releasing as %s, as requested by Release-As", forced.String())
+		if r.config.Strategy.Type == "full-release" {
+			return forced, nil
+		}
+		return r.nextCandidate(ctx, *forced, target.Channel)
+	}
+
+	level := r.Level(changes)
+	graduate := false
+	if r.config.Versioning.ZeroMajorBumpsMinor && current.Major() == 0 {
+		graduate = r.Graduates(changes)
+		if graduate && target.Line != nil {
+			r.log.Warnf("Changes ask to graduate to 1.0.0, which is outside line '%s'. Ignoring", target.Line.Name)
+			graduate = false
+		}
+		if !graduate && level > LevelPatch {
+			r.log.Debugf("Changes request a %s bump. Bumping %s before 1.0.0", level, level-1)
+			level--
 		}
 	}
+	if level == LevelNone && !graduate {
+		return nil, ErrNoBump
+	}
+	if target.Line != nil && level > target.Line.Max {
+		r.log.Warnf("Changes request a %s bump, which is not allowed in line '%s'. Bumping %s", level, target.Line.Name, target.Line.Max)
+		level = target.Line.Max
+	}
+
+	next := r.scheme.Next(current, level)
+	if graduate {
+		r.log.Infof("Graduating from %s to 1.0.0", current.String())
+		next = *semver.MustParse("1.0.0")
+	}
 
 	if r.config.Strategy.Type == "full-release" {
 		return &next, nil
 	}
 
-	prereleases, err := r.client.GetRefs(ctx, fmt.Sprintf("tags/v%s-rc.", next.String()))
+	return r.nextCandidate(ctx, next, target.Channel)
+}
+
+// nextCandidate finds the next free candidate number of a version in a channel
+func (r *Releaser) nextCandidate(ctx context.Context, version semver.Version, channel string) (*semver.Version, error) {
+	versions, err := r.versions(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve pre-releases")
 	}
 
 	rc := 1
-	for _, r := range prereleases {
-		result := candidateRx.FindStringSubmatch(strings.TrimPrefix(r.GetRef(), fmt.Sprintf("refs/tags/v%s-", next)))
-		nextrc, err := strconv.Atoi(result[1])
+	for _, v := range versions {
+		result := candidateRx.FindStringSubmatch(v.Version.Prerelease())
+		if result == nil || result[candidateRx.SubexpIndex("channel")] != channel {
+			continue
+		}
+		if core, _ := v.Version.SetPrerelease(""); !core.Equal(&version) {
+			continue
+		}
+		nextrc, err := strconv.Atoi(result[candidateRx.SubexpIndex("candidate")])
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to read pre-release number")
 		}
@@ -393,20 +731,9 @@ out:
 		}
 	}
 
-	return semver.NewVersion(fmt.Sprintf("v%s-rc.%d", next, rc))
-}
-
-func (r *Releaser) CollectChangelog(pulls []*github.PullRequest) (string, error) {
-	logs := []string{}
-	for _, p := range pulls {
-		matches := changelogRx.FindStringSubmatch(p.GetBody())
-		if len(matches) < 2 {
-			continue
-		}
-		desc := strings.TrimSpace(matches[1])
-		if strings.ToLower(desc) != "none" || desc != "" {
-			logs = append(logs, fmt.Sprintf("- #%d %s", p.GetNumber(), desc))
-		}
+	next, err := version.SetPrerelease(fmt.Sprintf("%s.%d", channel, rc))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to set pre-release '%s.%d'", channel, rc)
 	}
-	return fmt.Sprintf("Changes:\n\n%s", strings.Join(logs, "\n")), nil
+	return &next, nil
 }
@@ -0,0 +1,116 @@
+package scm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v43/github"
+)
+
+var (
+	// revertPullRx matches the body of pull requests made with the revert button on GitHub
+	revertPullRx   = regexp.MustCompile(`(?m)^Reverts (?P<repo>[\w.-]+/[\w.-]+)#(?P<number>[0-9]+)`)
+	revertCommitRx = regexp.MustCompile(`This reverts commit (?P<sha>[0-9a-fA-F]{7,40})`)
+)
+
+// changeKey identifies a change that can be reverted. Commits of a pull request
+// are identified by the pull request
+func changeKey(changes *Changes, sha string) string {
+	if pulls := changes.PullsByCommit[sha]; len(pulls) > 0 {
+		return fmt.Sprintf("#%d", pulls[0].GetNumber())
+	}
+	return fmt.Sprintf("%.7s", sha)
+}
+
+// reverts maps every change reverting another change in the same range to the
+// change it reverts
+func (r *Releaser) reverts(changes *Changes) map[string]string {
+	reverts := map[string]string{}
+	inRange := map[string]bool{}
+	for _, p := range changes.Pulls {
+		inRange[fmt.Sprintf("#%d", p.GetNumber())] = true
+	}
+	for _, p := range changes.Pulls {
+		for _, matches := range revertPullRx.FindAllStringSubmatch(p.GetBody(), -1) {
+			if !strings.EqualFold(matches[revertPullRx.SubexpIndex("repo")], r.client.GetRepo().GetFullName()) {
+				continue
+			}
+			number, err := strconv.Atoi(matches[revertPullRx.SubexpIndex("number")])
+			if err != nil {
+				continue
+			}
+			reverted := fmt.Sprintf("#%d", number)
+			if inRange[reverted] {
+				reverts[fmt.Sprintf("#%d", p.GetNumber())] = reverted
+			}
+		}
+	}
+	for _, c := range changes.Commits {
+		for _, matches := range revertCommitRx.FindAllStringSubmatch(c.GetCommit().GetMessage(), -1) {
+			sha := strings.ToLower(matches[revertCommitRx.SubexpIndex("sha")])
+			for _, target := range changes.Commits {
+				if !strings.HasPrefix(target.GetSHA(), sha) {
+					continue
+				}
+				revert, reverted := changeKey(changes, c.GetSHA()), changeKey(changes, target.GetSHA())
+				if revert != reverted {
+					reverts[revert] = reverted
+				}
+				break
+			}
+		}
+	}
+	return reverts
+}
+
+// DropReverts removes changes reverted within the range along with their reverts.
+// A revert of a revert brings the original change back
+func (r *Releaser) DropReverts(changes *Changes) *Changes {
+	reverts := r.reverts(changes)
+	if len(reverts) == 0 {
+		return changes
+	}
+	revertedBy := map[string]string{}
+	for revert, reverted := range reverts {
+		revertedBy[reverted] = revert
+	}
+	// effective tells whether a change still has an effect, i.e. it is not reverted
+	// by a change that itself has an effect
+	var effective func(key string, depth int) bool
+	effective = func(key string, depth int) bool {
+		revert, ok := revertedBy[key]
+		if !ok || depth > len(reverts) {
+			return true
+		}
+		return !effective(revert, depth+1)
+	}
+	dropped := func(key string) bool {
+		_, isRevert := reverts[key]
+		return isRevert || !effective(key, 0)
+	}
+
+	filtered := &Changes{
+		Commits:       []*github.RepositoryCommit{},
+		Pulls:         []*github.PullRequest{},
+		PullsByCommit: changes.PullsByCommit,
+		Reverted:      changes.Reverted,
+	}
+	for _, c := range changes.Commits {
+		if key := changeKey(changes, c.GetSHA()); dropped(key) {
+			r.log.Debugf("Dropping reverted change %s from commit '%.7s'", key, c.GetSHA())
+			filtered.Reverted++
+			continue
+		}
+		filtered.Commits = append(filtered.Commits, c)
+	}
+	for _, p := range changes.Pulls {
+		if dropped(fmt.Sprintf("#%d", p.GetNumber())) {
+			continue
+		}
+		filtered.Pulls = append(filtered.Pulls, p)
+	}
+	r.log.Infof("Dropped %d commits and %d pull requests cancelled out by reverts", len(changes.Commits)-len(filtered.Commits), len(changes.Pulls)-len(filtered.Pulls))
+	return filtered
+}
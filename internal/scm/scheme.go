@@ -0,0 +1,143 @@
+package scm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+// Scheme does the version math of a versioning scheme. Versions of every scheme
+// are represented as semantic versions, so pre-releases, candidates and ordering
+// work the same way
+type Scheme interface {
+	// Next bumps the current version by the level
+	Next(current *semver.Version, level Level) semver.Version
+	// Format renders a version as it appears in tags and release names
+	Format(v *semver.Version) string
+	// Initial finds the version of the first release from the configured initial version
+	Initial(configured *semver.Version) semver.Version
+}
+
+func newScheme(conf VersioningConf) (Scheme, error) {
+	switch conf.Scheme {
+	case "calver":
+		return newCalverScheme(conf.CalVer, time.Now)
+	default:
+		return semverScheme{}, nil
+	}
+}
+
+type semverScheme struct{}
+
+func (semverScheme) Next(current *semver.Version, level Level) semver.Version {
+	switch level {
+	case LevelMajor:
+		return current.IncMajor()
+	case LevelMinor:
+		return current.IncMinor()
+	default:
+		return current.IncPatch()
+	}
+}
+
+func (semverScheme) Format(v *semver.Version) string {
+	return v.String()
+}
+
+func (semverScheme) Initial(configured *semver.Version) semver.Version {
+	return *configured
+}
+
+// calverScheme versions by date, e.g. YYYY.MM.MICRO. The two date segments are
+// stored as major and minor, and MICRO counts releases within the same period
+type calverScheme struct {
+	segments [2]string
+	now      func() time.Time
+}
+
+var calverSegments = map[string]bool{
+	"YYYY": true,
+	"YY":   true,
+	"0Y":   true,
+	"MM":   true,
+	"0M":   true,
+	"WW":   true,
+	"0W":   true,
+	"DD":   true,
+	"0D":   true,
+}
+
+func newCalverScheme(format string, now func() time.Time) (*calverScheme, error) {
+	parts := strings.Split(format, ".")
+	if len(parts) != 3 || parts[2] != "MICRO" {
+		return nil, errors.Errorf("Calendar version format '%s' must be on the form <date>.<date>.MICRO", format)
+	}
+	for _, p := range parts[:2] {
+		if !calverSegments[p] {
+			return nil, errors.Errorf("Unknown segment '%s' in calendar version format '%s'", p, format)
+		}
+	}
+	return &calverScheme{
+		segments: [2]string{parts[0], parts[1]},
+		now:      now,
+	}, nil
+}
+
+func (s *calverScheme) weekly() bool {
+	return s.segments[0] == "WW" || s.segments[0] == "0W" || s.segments[1] == "WW" || s.segments[1] == "0W"
+}
+
+// value reads a segment from a date. Weekly formats use the ISO week year, so the
+// weeks around new year do not jump back in time
+func (s *calverScheme) value(segment string, t time.Time) uint64 {
+	year, week := t.ISOWeek()
+	if !s.weekly() {
+		year = t.Year()
+	}
+	switch segment {
+	case "YYYY":
+		return uint64(year)
+	case "YY", "0Y":
+		return uint64(year % 100)
+	case "MM", "0M":
+		return uint64(t.Month())
+	case "WW", "0W":
+		return uint64(week)
+	default:
+		return uint64(t.Day())
+	}
+}
+
+func (s *calverScheme) Next(current *semver.Version, level Level) semver.Version {
+	now := s.now()
+	major, minor := s.value(s.segments[0], now), s.value(s.segments[1], now)
+	if current.Major() == major && current.Minor() == minor {
+		return current.IncPatch()
+	}
+	return *semver.MustParse(fmt.Sprintf("%d.%d.0", major, minor))
+}
+
+// Initial ignores the configured version, as calendar versions start from the date
+func (s *calverScheme) Initial(configured *semver.Version) semver.Version {
+	return s.Next(&semver.Version{}, LevelPatch)
+}
+
+func (s *calverScheme) Format(v *semver.Version) string {
+	segment := func(format string, value uint64) string {
+		if strings.HasPrefix(format, "0") {
+			return fmt.Sprintf("%02d", value)
+		}
+		return fmt.Sprintf("%d", value)
+	}
+	version := fmt.Sprintf("%s.%s.%d", segment(s.segments[0], v.Major()), segment(s.segments[1], v.Minor()), v.Patch())
+	if v.Prerelease() != "" {
+		version = fmt.Sprintf("%s-%s", version, v.Prerelease())
+	}
+	if v.Metadata() != "" {
+		version = fmt.Sprintf("%s+%s", version, v.Metadata())
+	}
+	return version
+}
@@ -0,0 +1,76 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v43/github"
+)
+
+const skipDirective = "[skip release]"
+
+// pullSkipped tells whether a pull request opted out of releasing, either by the
+// skip label or the skip directive in its title or body
+func (r *Releaser) pullSkipped(p *github.PullRequest) bool {
+	if strings.Contains(p.GetTitle(), skipDirective) || strings.Contains(p.GetBody(), skipDirective) {
+		return true
+	}
+	for _, l := range p.Labels {
+		if r.config.Labels.Skip.Has(l.GetName()) {
+			return true
+		}
+	}
+	return false
+}
+
+// commitSkipped tells whether a commit opted out of releasing, either by the skip
+// directive in its message or by every one of its pull requests opting out
+func (r *Releaser) commitSkipped(c *github.RepositoryCommit, pulls []*github.PullRequest) bool {
+	if strings.Contains(c.GetCommit().GetMessage(), skipDirective) {
+		return true
+	}
+	if len(pulls) == 0 {
+		return false
+	}
+	for _, p := range pulls {
+		if !r.pullSkipped(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Skip decides whether to skip releasing the changes, which is the case when every
+// commit opted out. The reason explains the decision whenever anything opted out
+func (r *Releaser) Skip(changes *Changes) (bool, string) {
+	skipped := 0
+	var releasing *github.RepositoryCommit
+	for _, c := range changes.Commits {
+		if r.commitSkipped(c, changes.PullsByCommit[c.GetSHA()]) {
+			skipped++
+		} else if releasing == nil {
+			releasing = c
+		}
+	}
+	if skipped == 0 {
+		return false, ""
+	}
+	if releasing == nil {
+		if len(r.config.Labels.Skip) == 0 {
+			return true, fmt.Sprintf("all %d commits opted out with '%s'", skipped, skipDirective)
+		}
+		return true, fmt.Sprintf("all %d commits opted out with '%s' or the labels '%s'", skipped, skipDirective, strings.Join(r.config.Labels.Skip, "', '"))
+	}
+	return false, fmt.Sprintf("%d of %d commits opted out, but commit '%.7s' did not", skipped, len(changes.Commits), releasing.GetSHA())
+}
+
+// pushed collects the changes of the component in a push, which decide whether
+// to skip releasing. Pushes creating the branch fall back to the changes being
+// released, as they have no previous commit to compare with
+func (r *Releaser) pushed(ctx context.Context, e *github.PushEvent, changes *Changes) (*Changes, error) {
+	if e.GetCreated() || strings.Trim(e.GetBefore(), "0") == "" {
+		return changes, nil
+	}
+	return r.Changes(ctx, e.GetBefore(), e.GetAfter())
+}
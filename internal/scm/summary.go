@@ -0,0 +1,127 @@
+package scm
+
+import (
+	"context"
+
+	"github.com/google/go-github/v43/github"
+	"github.com/pkg/errors"
+)
+
+var ErrComponentMissing = errors.New("Component not found")
+
+// Summary describes the changes between two refs, as they would be released
+type Summary struct {
+	From  string        `json:"from"`
+	To    string        `json:"to"`
+	Level string        `json:"level"`
+	Pulls []SummaryPull `json:"pulls"`
+	// Commits are the commits pushed without a pull request
+	Commits []SummaryCommit `json:"commits"`
+
+	releaser *Releaser
+	changes  *Changes
+}
+
+type SummaryPull struct {
+	Number int           `json:"number"`
+	Title  string        `json:"title"`
+	Author string        `json:"author"`
+	URL    string        `json:"url"`
+	Labels []string      `json:"labels"`
+	Level  string        `json:"level"`
+	Notes  []SummaryNote `json:"notes"`
+	// Dependency is set for pull requests of dependency bots
+	Dependency bool `json:"dependency"`
+}
+
+type SummaryCommit struct {
+	SHA     string `json:"sha"`
+	Message string `json:"message"`
+	Author  string `json:"author"`
+	URL     string `json:"url"`
+	Level   string `json:"level"`
+}
+
+type SummaryNote struct {
+	Kind string `json:"kind,omitempty"`
+	Text string `json:"text"`
+}
+
+// Summarize collects the changes of a component between two refs. The component
+// is named, unless the repository has no components. From defaults to the latest
+// release of the component, and to defaults to the target branch
+func (r *Releaser) Summarize(ctx context.Context, name, from, to string) (*Summary, error) {
+	var c *component
+	for _, candidate := range r.components {
+		if candidate.Name == name {
+			c = candidate
+			break
+		}
+	}
+	if c == nil && name == "" {
+		return nil, errors.Wrap(ErrComponentMissing, "The repository has components, so one must be chosen")
+	}
+	if c == nil {
+		return nil, errors.Wrapf(ErrComponentMissing, "No component named '%s'", name)
+	}
+	cr := r.forComponent(c)
+
+	if from == "" {
+		tag, _, err := cr.LatestVersion(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		from = tag
+	}
+	if to == "" {
+		to = r.config.TargetBranch
+	}
+	changes, err := cr.Changes(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		From:     from,
+		To:       to,
+		Level:    cr.Level(changes).String(),
+		Pulls:    []SummaryPull{},
+		Commits:  []SummaryCommit{},
+		releaser: cr,
+		changes:  changes,
+	}
+	for _, p := range changes.Pulls {
+		pull := SummaryPull{
+			Number:     p.GetNumber(),
+			Title:      p.GetTitle(),
+			Author:     p.GetUser().GetLogin(),
+			URL:        p.GetHTMLURL(),
+			Labels:     []string{},
+			Level:      cr.Level(&Changes{Pulls: []*github.PullRequest{p}}).String(),
+			Notes:      []SummaryNote{},
+			Dependency: cr.IsDependency(p),
+		}
+		for _, l := range p.Labels {
+			pull.Labels = append(pull.Labels, l.GetName())
+		}
+		for _, n := range releaseNotes(p.GetBody()) {
+			pull.Notes = append(pull.Notes, SummaryNote{Kind: n.Kind, Text: n.Text})
+		}
+		summary.Pulls = append(summary.Pulls, pull)
+	}
+	for _, c := range cr.DirectCommits(changes) {
+		summary.Commits = append(summary.Commits, SummaryCommit{
+			SHA:     c.GetSHA(),
+			Message: c.GetCommit().GetMessage(),
+			Author:  c.GetCommit().GetAuthor().GetName(),
+			URL:     c.GetHTMLURL(),
+			Level:   directLevel(c.GetCommit().GetMessage()).String(),
+		})
+	}
+	return summary, nil
+}
+
+// Markdown renders the summary with the changelog template
+func (s *Summary) Markdown(ctx context.Context) (string, error) {
+	return s.releaser.CollectChangelog(ctx, s.changes, nil, s.To, s.From)
+}
@@ -0,0 +1,142 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	versionSentinel = "\x00version\x00"
+	dateSentinel    = "\x00date\x00"
+)
+
+// Tagger renders tags and release names from the configured templates, and reads
+// versions back from tags rendered by the same template
+type Tagger struct {
+	tag       *template.Template
+	name      *template.Template
+	rx        *regexp.Regexp
+	prefix    string
+	component string
+	scheme    Scheme
+}
+
+type tagData struct {
+	Component string
+	Version   string
+	Date      string
+}
+
+// taggedVersion is a version along with the tag it was read from
+type taggedVersion struct {
+	Tag     string
+	Version *semver.Version
+}
+
+func NewTagger(tagFormat, nameFormat, component string, scheme Scheme) (*Tagger, error) {
+	tag, err := template.New("tag").Option("missingkey=error").Parse(tagFormat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse tag format '%s'", tagFormat)
+	}
+	name, err := template.New("name").Option("missingkey=error").Parse(nameFormat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse release name format '%s'", nameFormat)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tag.Execute(buf, tagData{Component: component, Version: versionSentinel, Date: dateSentinel}); err != nil {
+		return nil, errors.Wrapf(err, "Failed to render tag format '%s'", tagFormat)
+	}
+	sample := buf.String()
+	if strings.Count(sample, versionSentinel) != 1 {
+		return nil, errors.Errorf("Tag format '%s' must contain {{.Version}} exactly once", tagFormat)
+	}
+
+	prefix := sample
+	if i := strings.Index(prefix, "\x00"); i >= 0 {
+		prefix = prefix[:i]
+	}
+	pattern := regexp.QuoteMeta(sample)
+	pattern = strings.Replace(pattern, versionSentinel, `(?P<version>[0-9A-Za-z.+-]+?)`, 1)
+	pattern = strings.ReplaceAll(pattern, dateSentinel, `[0-9-]+`)
+	rx, err := regexp.Compile(fmt.Sprintf("^%s$", pattern))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to build tag pattern from format '%s'", tagFormat)
+	}
+
+	return &Tagger{
+		tag:       tag,
+		name:      name,
+		rx:        rx,
+		prefix:    prefix,
+		component: component,
+		scheme:    scheme,
+	}, nil
+}
+
+func (t *Tagger) render(tmpl *template.Template, v *semver.Version) (string, error) {
+	buf := &bytes.Buffer{}
+	err := tmpl.Execute(buf, tagData{
+		Component: t.component,
+		Version:   t.scheme.Format(v),
+		Date:      time.Now().Format("2006-01-02"),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to render '%s' for version '%s'", tmpl.Name(), t.scheme.Format(v))
+	}
+	return buf.String(), nil
+}
+
+// Tag renders the tag of a version
+func (t *Tagger) Tag(v *semver.Version) (string, error) {
+	return t.render(t.tag, v)
+}
+
+// Name renders the release name of a version
+func (t *Tagger) Name(v *semver.Version) (string, error) {
+	return t.render(t.name, v)
+}
+
+// Parse reads the version from a tag rendered by the tag format
+func (t *Tagger) Parse(tag string) (*semver.Version, error) {
+	matches := t.rx.FindStringSubmatch(tag)
+	if matches == nil {
+		return nil, errors.Errorf("Tag '%s' does not match the tag format", tag)
+	}
+	version, err := semver.NewVersion(matches[t.rx.SubexpIndex("version")])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse tag '%s' as semantic version", tag)
+	}
+	return version, nil
+}
+
+// Prefix is the literal start of every tag rendered by the tag format
+func (t *Tagger) Prefix() string {
+	return t.prefix
+}
+
+// versions lists every tag in the repository matching the tag format
+func (r *Releaser) versions(ctx context.Context) ([]taggedVersion, error) {
+	refs, err := r.client.GetRefs(ctx, fmt.Sprintf("tags/%s", r.tagger.Prefix()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list references with pattern 'tags/%s'", r.tagger.Prefix())
+	}
+	versions := []taggedVersion{}
+	for _, ref := range refs {
+		tag := strings.TrimPrefix(ref.GetRef(), "refs/tags/")
+		v, err := r.tagger.Parse(tag)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, taggedVersion{Tag: tag, Version: v})
+	}
+	return versions, nil
+}
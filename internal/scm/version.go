@@ -0,0 +1,270 @@
+package scm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v43/github"
+	"github.com/pkg/errors"
+)
+
+// Level is the part of the version a release bumps
+type Level int
+
+const (
+	LevelNone Level = iota
+	LevelPatch
+	LevelMinor
+	LevelMajor
+)
+
+var (
+	ErrNoBump = errors.New("No changes require a version bump")
+
+	conventionalRx = regexp.MustCompile(`^(?P<type>[a-zA-Z]+)(\([^)]*\))?(?P<breaking>!)?: `)
+	breakingRx     = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+	releaseAsRx    = regexp.MustCompile(`(?mi)^Release-As:[ \t]*(?P<version>\S*)[ \t]*\r?$`)
+	releaseBumpRx  = regexp.MustCompile(`(?mi)^Release-Bump:[ \t]*(?P<level>major|minor|patch|none)[ \t]*\r?$`)
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelMajor:
+		return "major"
+	case LevelMinor:
+		return "minor"
+	case LevelPatch:
+		return "patch"
+	default:
+		return "none"
+	}
+}
+
+// Changes are the commits and pull requests going into a release
+type Changes struct {
+	Commits       []*github.RepositoryCommit
+	Pulls         []*github.PullRequest
+	PullsByCommit map[string][]*github.PullRequest
+	// Reverted counts the commits dropped as they were cancelled out by reverts
+	Reverted int
+}
+
+// direct tells whether a commit was pushed without a pull request. Commits whose
+// pull requests were never looked up are not direct
+func (c *Changes) direct(sha string) bool {
+	pulls, ok := c.PullsByCommit[sha]
+	return ok && len(pulls) == 0
+}
+
+// conventionalLevel reads the level requested by a conventional commit message,
+// e.g. "feat(api)!: drop v1". Messages not on the conventional form request a patch.
+func conventionalLevel(message string) Level {
+	if breakingRx.MatchString(message) {
+		return LevelMajor
+	}
+	matches := conventionalRx.FindStringSubmatch(message)
+	if matches == nil {
+		return LevelPatch
+	}
+	if matches[conventionalRx.SubexpIndex("breaking")] != "" {
+		return LevelMajor
+	}
+	if strings.ToLower(matches[conventionalRx.SubexpIndex("type")]) == "feat" {
+		return LevelMinor
+	}
+	return LevelPatch
+}
+
+// directLevel reads the level requested by a commit pushed without a pull
+// request. A Release-Bump trailer wins over the conventional commit prefix
+func directLevel(message string) Level {
+	if matches := releaseBumpRx.FindStringSubmatch(message); matches != nil {
+		switch strings.ToLower(matches[releaseBumpRx.SubexpIndex("level")]) {
+		case "major":
+			return LevelMajor
+		case "minor":
+			return LevelMinor
+		case "patch":
+			return LevelPatch
+		default:
+			return LevelNone
+		}
+	}
+	return conventionalLevel(message)
+}
+
+// LabelLevel finds the level requested by the labels of a pull request. Pull
+// requests without bump labels request a patch. When labels of several levels are
+// present, the highest wins and the conflicting labels are returned
+func (r *Releaser) LabelLevel(p *github.PullRequest) (Level, []string) {
+	levels := []struct {
+		level  Level
+		labels LabelList
+	}{
+		{LevelMajor, r.config.Labels.Major},
+		{LevelMinor, r.config.Labels.Minor},
+		{LevelPatch, r.config.Labels.Patch},
+		{LevelNone, r.config.Labels.None},
+	}
+	requested := []Level{}
+	found := []string{}
+	for _, l := range levels {
+		for _, label := range p.Labels {
+			if l.labels.Has(label.GetName()) {
+				requested = append(requested, l.level)
+				found = append(found, label.GetName())
+				break
+			}
+		}
+	}
+	if len(requested) == 0 {
+		return LevelPatch, nil
+	}
+	if len(requested) > 1 {
+		return requested[0], found
+	}
+	return requested[0], nil
+}
+
+// conventionalLimit is the highest level the conventional title and commits of a
+// pull request may request. Pull requests labelled with a none label request none
+func (r *Releaser) conventionalLimit(p *github.PullRequest) Level {
+	if level, _ := r.LabelLevel(p); level == LevelNone {
+		return LevelNone
+	}
+	return r.levelLimit(p)
+}
+
+// releaseAs reads the version requested by a Release-As directive in a pull
+// request body or commit message, if there is one
+func releaseAs(text string) (*semver.Version, error) {
+	matches := releaseAsRx.FindStringSubmatch(text)
+	if matches == nil {
+		return nil, nil
+	}
+	version, err := semver.NewVersion(matches[releaseAsRx.SubexpIndex("version")])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid Release-As version '%s'", matches[releaseAsRx.SubexpIndex("version")])
+	}
+	if version.Prerelease() != "" || version.Metadata() != "" {
+		return nil, errors.Errorf("Release-As version '%s' must be a full release", version.Original())
+	}
+	return version, nil
+}
+
+// ReleaseAs finds the highest version forced by Release-As directives in the
+// changes. Directives must be valid versions greater than the current version
+func (r *Releaser) ReleaseAs(changes *Changes, current *semver.Version) (*semver.Version, error) {
+	var forced *semver.Version
+	check := func(text, source string) error {
+		version, err := releaseAs(text)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read Release-As directive of %s", source)
+		}
+		if version == nil {
+			return nil
+		}
+		if !version.GreaterThan(current) {
+			return errors.Errorf("Release-As version '%s' of %s is not greater than the current version '%s'", version.Original(), source, current.String())
+		}
+		if forced == nil || version.GreaterThan(forced) {
+			forced = version
+		}
+		return nil
+	}
+	for _, p := range changes.Pulls {
+		if err := check(p.GetBody(), fmt.Sprintf("pull request #%d", p.GetNumber())); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range changes.Commits {
+		if err := check(c.GetCommit().GetMessage(), fmt.Sprintf("commit '%.7s'", c.GetSHA())); err != nil {
+			return nil, err
+		}
+	}
+	return forced, nil
+}
+
+// Graduates tells whether any pull request asks to graduate a 0.x version to
+// 1.0.0 with a stable label
+func (r *Releaser) Graduates(changes *Changes) bool {
+	for _, p := range changes.Pulls {
+		for _, l := range p.Labels {
+			if r.config.Labels.Stable.Has(l.GetName()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Level finds the highest level requested by the changes, using the sources
+// configured in versioning.source. Typed release notes request a level of their
+// own, e.g. a breaking note requests a major bump, and so do commits pushed
+// without a pull request
+func (r *Releaser) Level(changes *Changes) Level {
+	level := LevelNone
+	raise := func(l Level) {
+		if l > level {
+			level = l
+		}
+	}
+	// raisePull raises by a level requested by a pull request, within a limit
+	raisePull := func(p *github.PullRequest, l, limit Level) {
+		if l > limit {
+			r.log.Debugf("Limiting level of pull request #%d from %s to %s", p.GetNumber(), l, limit)
+			l = limit
+		}
+		raise(l)
+	}
+	if r.config.Versioning.Source != "conventional-commits" {
+		for _, p := range changes.Pulls {
+			l, conflicts := r.LabelLevel(p)
+			if len(conflicts) > 0 {
+				r.log.Warnf("Pull request #%d has conflicting bump labels '%s'. Using %s", p.GetNumber(), strings.Join(conflicts, "', '"), l)
+			}
+			raisePull(p, l, r.levelLimit(p))
+		}
+	}
+	for _, c := range changes.Commits {
+		if changes.direct(c.GetSHA()) && !r.commitSkipped(c, nil) {
+			raise(directLevel(c.GetCommit().GetMessage()))
+		}
+	}
+	for _, p := range changes.Pulls {
+		for _, n := range releaseNotes(p.GetBody()) {
+			raisePull(p, n.Level(), r.levelLimit(p))
+		}
+	}
+	if r.config.Versioning.Source != "labels" {
+		for _, p := range changes.Pulls {
+			raisePull(p, conventionalLevel(p.GetTitle()), r.conventionalLimit(p))
+		}
+		for _, c := range changes.Commits {
+			// Direct commits are covered by directLevel, which respects Release-Bump
+			if changes.direct(c.GetSHA()) {
+				continue
+			}
+			l := conventionalLevel(c.GetCommit().GetMessage())
+			pulls := changes.PullsByCommit[c.GetSHA()]
+			if len(pulls) == 0 {
+				raise(l)
+				continue
+			}
+			// A commit shared with a regular pull request is not limited
+			limit := LevelNone
+			for _, p := range pulls {
+				if pl := r.conventionalLimit(p); pl > limit {
+					limit = pl
+				}
+			}
+			if l > limit {
+				l = limit
+			}
+			raise(l)
+		}
+	}
+	return level
+}
@@ -0,0 +1,239 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+var yamlKeyRx = regexp.MustCompile(`^(?P<indent>[ -]*)(?P<key>[^\s#:][^:]*?):(?P<space>[ \t]*)(?P<value>.*?)(?P<comment>[ \t]+#.*)?$`)
+
+// versionUpdater writes a version into the content of a version file
+type versionUpdater func(content []byte, version string) ([]byte, error)
+
+func newVersionUpdater(conf VersionFileConf) (versionUpdater, error) {
+	switch conf.Type {
+	case "json":
+		if conf.Key == "" {
+			return nil, errors.Errorf("Version file '%s' of type json needs a key", conf.Path)
+		}
+		return func(content []byte, version string) ([]byte, error) {
+			return setJSON(content, conf.Key, version)
+		}, nil
+	case "yaml":
+		if conf.Key == "" {
+			return nil, errors.Errorf("Version file '%s' of type yaml needs a key", conf.Path)
+		}
+		return func(content []byte, version string) ([]byte, error) {
+			return setYAML(content, conf.Key, version)
+		}, nil
+	case "regex":
+		rx, err := regexp.Compile(conf.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse pattern of version file '%s'", conf.Path)
+		}
+		if rx.NumSubexp() < 1 {
+			return nil, errors.Errorf("Pattern of version file '%s' must capture the version in a group", conf.Path)
+		}
+		return func(content []byte, version string) ([]byte, error) {
+			return setRegex(content, rx, version)
+		}, nil
+	default:
+		return nil, errors.Errorf("Unknown type '%s' of version file '%s'", conf.Type, conf.Path)
+	}
+}
+
+// setJSON replaces the string value at a dotted key path, leaving the rest of
+// the document untouched
+func setJSON(content []byte, key, version string) ([]byte, error) {
+	type frame struct {
+		object bool
+		key    string
+		index  int
+	}
+	target := strings.Split(key, ".")
+	stack := []*frame{}
+	path := func() []string {
+		keys := []string{}
+		for _, f := range stack {
+			if f.object {
+				keys = append(keys, f.key)
+			} else {
+				keys = append(keys, strconv.Itoa(f.index))
+			}
+		}
+		return keys
+	}
+	// expectKey tells whether the next string token is an object key
+	expectKey := false
+	valueDone := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		if top.object {
+			expectKey = true
+		} else {
+			top.index++
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode json")
+		}
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, &frame{object: true})
+				expectKey = true
+			case '[':
+				stack = append(stack, &frame{})
+				expectKey = false
+			default:
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+			continue
+		case string:
+			if expectKey {
+				stack[len(stack)-1].key = t
+				expectKey = false
+				continue
+			}
+			if strings.Join(path(), ".") != strings.Join(target, ".") {
+				break
+			}
+			end := decoder.InputOffset()
+			quote := int(start) + bytes.IndexByte(content[start:end], '"')
+			value, err := json.Marshal(version)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to encode version")
+			}
+			updated := append([]byte{}, content[:quote]...)
+			updated = append(updated, value...)
+			return append(updated, content[end:]...), nil
+		}
+		valueDone()
+	}
+	return nil, errors.Errorf("No string value found at key '%s'", key)
+}
+
+// setYAML replaces the scalar value at a dotted key path. The document is edited
+// line by line to keep comments and formatting
+func setYAML(content []byte, key, version string) ([]byte, error) {
+	type entry struct {
+		indent int
+		key    string
+	}
+	stack := []entry{}
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		matches := yamlKeyRx.FindStringSubmatch(line)
+		if matches == nil || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		indent := len(matches[yamlKeyRx.SubexpIndex("indent")])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, entry{indent: indent, key: strings.Trim(matches[yamlKeyRx.SubexpIndex("key")], `"'`)})
+
+		keys := []string{}
+		for _, e := range stack {
+			keys = append(keys, e.key)
+		}
+		value := matches[yamlKeyRx.SubexpIndex("value")]
+		if strings.Join(keys, ".") != key || value == "" {
+			continue
+		}
+		quoted := version
+		if strings.HasPrefix(value, `"`) {
+			quoted = strconv.Quote(version)
+		} else if strings.HasPrefix(value, "'") {
+			quoted = fmt.Sprintf("'%s'", version)
+		}
+		lines[i] = fmt.Sprintf("%s%s:%s%s%s",
+			matches[yamlKeyRx.SubexpIndex("indent")],
+			matches[yamlKeyRx.SubexpIndex("key")],
+			matches[yamlKeyRx.SubexpIndex("space")],
+			quoted,
+			matches[yamlKeyRx.SubexpIndex("comment")],
+		)
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+	return nil, errors.Errorf("No value found at key '%s'", key)
+}
+
+// setRegex replaces the first group of every match of the pattern
+func setRegex(content []byte, rx *regexp.Regexp, version string) ([]byte, error) {
+	matches := rx.FindAllSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return nil, errors.Errorf("Pattern '%s' did not match", rx.String())
+	}
+	updated := []byte{}
+	last := 0
+	replaced := 0
+	for _, m := range matches {
+		// The group is optional and did not take part in the match
+		if m[2] < 0 {
+			continue
+		}
+		updated = append(updated, content[last:m[2]]...)
+		updated = append(updated, version...)
+		last = m[3]
+		replaced++
+	}
+	if replaced == 0 {
+		return nil, errors.Errorf("The first group of pattern '%s' did not match", rx.String())
+	}
+	return append(updated, content[last:]...), nil
+}
+
+// BumpFiles writes the version into the version files of the component, and
+// commits them on top of the given commit. The commit is not pushed to any branch,
+// but only tagged by the release. Returns the SHA of the new commit
+func (r *Releaser) BumpFiles(ctx context.Context, sha, tagname string, version *semver.Version) (string, error) {
+	files := map[string][]byte{}
+	for _, f := range r.component.versionFiles {
+		content, ok := files[f.Path]
+		if !ok {
+			reader, err := r.client.GetFile(ctx, sha, f.Path)
+			if err != nil {
+				return "", errors.Wrapf(err, "Failed to get version file '%s'", f.Path)
+			}
+			content, err = ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return "", errors.Wrapf(err, "Failed to read version file '%s'", f.Path)
+			}
+		}
+		updated, err := f.update(content, r.scheme.Format(version))
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to update version file '%s'", f.Path)
+		}
+		files[f.Path] = updated
+	}
+
+	commit, err := r.client.CreateCommit(ctx, sha, fmt.Sprintf("Release %s", tagname), files)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to commit version files")
+	}
+	return commit, nil
+}